@@ -0,0 +1,249 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+	"github.com/urfave/cli/v2"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// allowedProjects maps allowed project config files to the SHA-256 of their allowed content,
+// so a file has to be allowed again after it changes.
+type allowedProjects map[string]string
+
+// allowedProjectsPath returns the file allowed project configs are recorded in.
+func allowedProjectsPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "allowed.json"), nil
+}
+
+func readAllowedProjects(path string) (allowedProjects, error) {
+	allowed := allowedProjects{}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return allowed, nil
+	}
+
+	if err != nil {
+		return allowed, err
+	}
+
+	if err := json.Unmarshal(b, &allowed); err != nil {
+		return allowed, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return allowed, nil
+}
+
+func writeAllowedProjects(path string, allowed allowedProjects) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(allowed, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0600)
+}
+
+func contentHash(b []byte) string {
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// isAllowed reports whether the project config at path is allowed with its current content.
+func (a allowedProjects) isAllowed(path string, content []byte) bool {
+	return a[path] == contentHash(content)
+}
+
+// projectAllowed reports whether the project config at path was allowed with its current content.
+var projectAllowed = func(path string, content []byte) bool {
+	allowedPath, err := allowedProjectsPath()
+	if err != nil {
+		return false
+	}
+
+	allowed, err := readAllowedProjects(allowedPath)
+
+	return err == nil && allowed.isAllowed(path, content)
+}
+
+// projectConfigFor returns the project config that applies in dir, or "" when there is none.
+func projectConfigFor(dir string) string {
+	home, err := userHomeDir()
+	if err != nil {
+		home = ""
+	}
+
+	return findProjectConfig(dir, userConfigPaths(home, os.LookupEnv))
+}
+
+// projectTarget returns the context and namespace a project config asks for.
+func projectTarget(path string, content []byte) (context, namespace string, err error) {
+	cfg, err := parseConfig(path, content)
+	if err != nil {
+		return "", "", err
+	}
+
+	return cfg.Context, cfg.Namespace, nil
+}
+
+// sessionTarget returns the current context of a session kubeconfig and its namespace.
+func sessionTarget(path string) (context, namespace string) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", ""
+	}
+
+	var config apiv1.Config
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return "", ""
+	}
+
+	ctx := contextsMap(config.Contexts)[config.CurrentContext]
+
+	return config.CurrentContext, ctx.Namespace
+}
+
+// hookAction is run by the shell integration whenever the working directory changes.
+// Inside a session it switches to the context named by the nearest project config,
+// provided the config was allowed with 'ksw allow'.
+func hookAction(c *cli.Context) error {
+	sessionKubeconfig := os.Getenv("KSW_KUBECONFIG")
+	if os.Getenv("KSW_KUBECONFIG_ORIGINAL") == "" || sessionKubeconfig == "" {
+		return nil
+	}
+
+	dir, err := workingDir()
+	if err != nil {
+		return nil
+	}
+
+	path := projectConfigFor(dir)
+	if path == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	contextName, namespace, err := projectTarget(path, content)
+	if err != nil || contextName == "" {
+		return nil
+	}
+
+	currentContext, currentNamespace := sessionTarget(sessionKubeconfig)
+	if contextName == currentContext && (namespace == "" || namespace == currentNamespace) {
+		return nil
+	}
+
+	allowedPath, err := allowedProjectsPath()
+	if err != nil {
+		return nil
+	}
+
+	allowed, err := readAllowedProjects(allowedPath)
+	if err != nil {
+		logf("warning: %v", err)
+
+		return nil
+	}
+
+	if !allowed.isAllowed(path, content) {
+		logf("%s targets context %s but is not allowed, run 'ksw allow' to switch automatically", path, contextName)
+
+		return nil
+	}
+
+	return switchContext(contextName, sessionOptions{Namespace: namespace})
+}
+
+// allowAction allows or denies the project config that applies in the given or current directory.
+func allowAction(allow bool) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		dir := c.Args().First()
+		if dir == "" {
+			wd, err := workingDir()
+			if err != nil {
+				return err
+			}
+
+			dir = wd
+		}
+
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+
+		path := projectConfigFor(dir)
+		if path == "" {
+			return fmt.Errorf("no %s found in %s or its parents", projectConfigName, dir)
+		}
+
+		allowedPath, err := allowedProjectsPath()
+		if err != nil {
+			return err
+		}
+
+		allowed, err := readAllowedProjects(allowedPath)
+		if err != nil {
+			return err
+		}
+
+		if !allow {
+			delete(allowed, path)
+
+			if err := writeAllowedProjects(allowedPath, allowed); err != nil {
+				return err
+			}
+
+			logf("%s is no longer allowed", path)
+
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		contextName, namespace, err := projectTarget(path, content)
+		if err != nil {
+			return err
+		}
+
+		allowed[path] = contentHash(content)
+
+		if err := writeAllowedProjects(allowedPath, allowed); err != nil {
+			return err
+		}
+
+		switch {
+		case contextName == "":
+			logf("allowed %s", path)
+		case namespace == "":
+			logf("allowed %s, it switches to context %s", path, contextName)
+		default:
+			logf("allowed %s, it switches to context %s and namespace %s", path, contextName, namespace)
+		}
+
+		return nil
+	}
+}
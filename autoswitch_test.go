@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const autoswitchKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+- name: staging
+  context:
+    cluster: staging
+    user: staging
+    namespace: default
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+- name: staging
+  cluster:
+    server: https://staging.example.com
+users:
+- name: dev
+  user:
+    token: dev-token
+- name: staging
+  user:
+    token: staging-token
+`
+
+func TestAllowedProjects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ksw", "allowed.json")
+
+	allowed, err := readAllowedProjects(path)
+	if err != nil {
+		t.Fatalf("readAllowedProjects() error = %v", err)
+	}
+
+	allowed["/src/app/.ksw.yaml"] = contentHash([]byte("context: dev\n"))
+
+	if err := writeAllowedProjects(path, allowed); err != nil {
+		t.Fatalf("writeAllowedProjects() error = %v", err)
+	}
+
+	allowed, err = readAllowedProjects(path)
+	if err != nil {
+		t.Fatalf("readAllowedProjects() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    bool
+	}{
+		{name: "same content", path: "/src/app/.ksw.yaml", content: "context: dev\n", want: true},
+		{name: "changed content", path: "/src/app/.ksw.yaml", content: "context: prod\n", want: false},
+		{name: "other project", path: "/src/other/.ksw.yaml", content: "context: dev\n", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowed.isAllowed(tt.path, []byte(tt.content)); got != tt.want {
+				t.Errorf("isAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHookAction(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "project")
+	projectConfig := filepath.Join(project, ".ksw.yaml")
+	original := filepath.Join(root, "config")
+	session := filepath.Join(root, "session.yaml")
+
+	writeTestFile(t, projectConfig, "context: staging\nnamespace: payments\n")
+	writeTestFile(t, original, autoswitchKubeconfig)
+
+	b, err := generateKubeconfig(original, "dev")
+	if err != nil {
+		t.Fatalf("generateKubeconfig() error = %v", err)
+	}
+
+	writeTestFile(t, session, string(b))
+
+	t.Setenv("XDG_STATE_HOME", filepath.Join(root, "state"))
+	t.Setenv("KSW_KUBECONFIG_ORIGINAL", original)
+	t.Setenv("KSW_KUBECONFIG", session)
+	t.Setenv("KSW_ENV_FILE", "")
+
+	origUserHomeDir, origWorkingDir := userHomeDir, workingDir
+	defer func() { userHomeDir, workingDir = origUserHomeDir, origWorkingDir }()
+
+	userHomeDir = func() (string, error) { return filepath.Join(root, "home"), nil }
+	workingDir = func() (string, error) { return project, nil }
+
+	if err := hookAction(nil); err != nil {
+		t.Fatalf("hookAction() error = %v", err)
+	}
+
+	if ctx, _ := sessionTarget(session); ctx != "dev" {
+		t.Errorf("hookAction() switched to %q before the project was allowed", ctx)
+	}
+
+	allowedPath, err := allowedProjectsPath()
+	if err != nil {
+		t.Fatalf("allowedProjectsPath() error = %v", err)
+	}
+
+	content, err := os.ReadFile(projectConfig)
+	if err != nil {
+		t.Fatalf("Failed to read project config: %v", err)
+	}
+
+	if err := writeAllowedProjects(allowedPath, allowedProjects{projectConfig: contentHash(content)}); err != nil {
+		t.Fatalf("writeAllowedProjects() error = %v", err)
+	}
+
+	if err := hookAction(nil); err != nil {
+		t.Fatalf("hookAction() error = %v", err)
+	}
+
+	if ctx, ns := sessionTarget(session); ctx != "staging" || ns != "payments" {
+		t.Errorf("sessionTarget() = %q, %q, want staging, payments", ctx, ns)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// The completion scripts always pass the word being completed, possibly empty, before
+// --generate-bash-completion so that ksw can tell flags, flag values and arguments apart.
+// A lone "--" is passed as "-" since urfave/cli would otherwise run the command instead.
+
+// bashCompletion keeps colons in words so that context names such as EKS ARNs complete whole.
+const bashCompletion = `_ksw_completion() {
+  local cur cword
+  local -a words
+  if declare -F _get_comp_words_by_ref >/dev/null 2>&1; then
+    _get_comp_words_by_ref -n =: cur words cword
+  else
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    words=("${COMP_WORDS[@]}")
+    cword=$COMP_CWORD
+  fi
+  local arg="$cur" IFS=$'\n'
+  [[ "$arg" == "--" ]] && arg="-"
+  COMPREPLY=($(compgen -W "$(command ksw "${words[@]:1:cword-1}" "$arg" --generate-bash-completion 2>/dev/null)" -- "$cur"))
+  if declare -F __ltrim_colon_completions >/dev/null 2>&1; then
+    __ltrim_colon_completions "$cur"
+  fi
+}
+complete -o bashdefault -o default -F _ksw_completion ksw
+`
+
+// zshCompletion falls back to file names when ksw has nothing to offer, such as for --kubeconfig.
+const zshCompletion = `_ksw() {
+  local -a opts
+  local arg="${(Q)words[CURRENT]}"
+  [[ "$arg" == "--" ]] && arg="-"
+  opts=("${(@f)$(command ksw "${(@Q)words[2,CURRENT-1]}" "$arg" --generate-bash-completion 2>/dev/null)}")
+  if [[ -n "${opts[1]}" ]]; then
+    compadd -a opts
+  else
+    _files
+  fi
+}
+compdef _ksw ksw
+`
+
+// fishCompletion only completes file names for the arguments and flags that take files.
+const fishCompletion = `function __ksw_complete
+    set -l args (commandline -opc)
+    set -e args[1]
+    set -l cur (commandline -ct)
+    test "$cur" = --; and set cur -
+    command ksw $args "$cur" --generate-bash-completion 2>/dev/null
+end
+complete -c ksw -f -a '(__ksw_complete)'
+complete -c ksw -n '__fish_seen_subcommand_from import validate' -F
+complete -c ksw -l kubeconfig -r -F
+complete -c ksw -l config -r -F
+`
+
+var completionScripts = map[string]string{
+	"bash": bashCompletion,
+	"zsh":  zshCompletion,
+	"fish": fishCompletion,
+}
+
+func completionAction(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return fmt.Errorf("shell name required: bash, zsh or fish")
+	}
+
+	script, ok := completionScripts[name]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q: expected bash, zsh or fish", name)
+	}
+
+	fmt.Print(script)
+
+	return nil
+}
+
+// completionCacheEntry records the contexts of a kubeconfig file as of its size and modification time.
+type completionCacheEntry struct {
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	Contexts []string  `json:"contexts"`
+}
+
+// completionCache maps kubeconfig paths to their contexts so that completing context names does
+// not parse, or decrypt, a kubeconfig again until it changes.
+type completionCache map[string]completionCacheEntry
+
+// completionCachePath returns the file completed context names are cached in.
+func completionCachePath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "completion.json"), nil
+}
+
+// readCompletionCache loads the completion cache from path. A missing or unreadable file is an empty cache.
+func readCompletionCache(path string) completionCache {
+	cache := completionCache{}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+
+	if err := json.Unmarshal(b, &cache); err != nil {
+		return completionCache{}
+	}
+
+	return cache
+}
+
+// writeCompletionCache saves the completion cache to path.
+func writeCompletionCache(path string, cache completionCache) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0600)
+}
+
+// cachedContexts returns the contexts of the kubeconfig at path, from the completion cache at
+// cachePath while the kubeconfig is unchanged. Failing to update the cache is not an error.
+func cachedContexts(cachePath, path string) ([]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := readCompletionCache(cachePath)
+
+	if entry, ok := cache[abs]; ok && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+		return entry.Contexts, nil
+	}
+
+	contexts, err := listContexts(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	cache[abs] = completionCacheEntry{Size: info.Size(), ModTime: info.ModTime(), Contexts: contexts}
+	_ = writeCompletionCache(cachePath, cache)
+
+	return contexts, nil
+}
+
+// cachedContextsList returns the union of the contexts of the kubeconfig files in pathList, a list
+// such as $KUBECONFIG, in order. Files that cannot be read are skipped, as kubectl does.
+func cachedContextsList(cachePath, pathList string) []string {
+	var contexts []string
+
+	for _, path := range filepath.SplitList(pathList) {
+		if path == "" {
+			continue
+		}
+
+		names, err := cachedContexts(cachePath, path)
+		if err != nil {
+			continue
+		}
+
+		for _, name := range names {
+			if !slices.Contains(contexts, name) {
+				contexts = append(contexts, name)
+			}
+		}
+	}
+
+	return contexts
+}
+
+// completionContexts returns the contexts to complete. The Before function does not run while
+// completing, so --kubeconfig is read from the command line here.
+func completionContexts(c *cli.Context) []string {
+	path := c.String("kubeconfig")
+	if path == "" {
+		path = getOriginalKubeconfigPath()
+	}
+
+	cachePath, err := completionCachePath()
+	if err != nil {
+		return nil
+	}
+
+	return loadConfig().allowedContexts(cachedContextsList(cachePath, path))
+}
+
+// completionWords returns the word being completed and the word before it.
+func completionWords(args []string) (string, string) {
+	// The last argument is --generate-bash-completion
+	var word, prev string
+
+	if len(args) > 2 {
+		word = args[len(args)-2]
+	}
+
+	if len(args) > 3 {
+		prev = args[len(args)-3]
+	}
+
+	return word, prev
+}
+
+// flagTakesValue reports whether arg is one of flags expecting a value as the next argument.
+func flagTakesValue(flags []cli.Flag, arg string) bool {
+	name, ok := strings.CutPrefix(arg, "-")
+	if !ok || strings.Contains(name, "=") {
+		return false
+	}
+
+	name = strings.TrimPrefix(name, "-")
+
+	for _, flag := range flags {
+		if slices.Contains(flag.Names(), name) {
+			_, isBool := flag.(*cli.BoolFlag)
+
+			return !isBool
+		}
+	}
+
+	return false
+}
+
+// completionTags returns the key=value pairs, or only the keys, of the tags of contexts.
+func completionTags(cfg KswConfig, contexts []string, keysOnly bool) []string {
+	seen := make(map[string]bool)
+
+	for _, ctx := range contexts {
+		for k, v := range cfg.contextTags(ctx) {
+			if keysOnly {
+				seen[k] = true
+			} else {
+				seen[k+"="+v] = true
+			}
+		}
+	}
+
+	return slices.Sorted(maps.Keys(seen))
+}
+
+func printCompletions(c *cli.Context, words []string) {
+	for _, word := range words {
+		fmt.Fprintln(c.App.Writer, word)
+	}
+}
+
+// completeMain completes the flags and their values, the subcommands and the context query of ksw.
+func completeMain(c *cli.Context) {
+	word, prev := completionWords(os.Args)
+
+	if strings.HasPrefix(word, "-") {
+		cli.DefaultAppComplete(c)
+
+		return
+	}
+
+	switch prev {
+	case "--output", "-o":
+		printCompletions(c, []string{"name", "wide"})
+
+		return
+	case "--tag":
+		printCompletions(c, completionTags(loadConfig(), completionContexts(c), false))
+
+		return
+	case "--group-by":
+		printCompletions(c, completionTags(loadConfig(), completionContexts(c), true))
+
+		return
+	}
+
+	// A query has already been given, or a flag expects a file
+	if c.NArg() > 1 || flagTakesValue(c.App.Flags, prev) {
+		return
+	}
+
+	cli.DefaultAppComplete(c)
+	printCompletions(c, completionContexts(c))
+}
+
+// completeContextArgs completes the flags of a subcommand and up to limit context names as its
+// arguments, or any number of them when limit is 0.
+func completeContextArgs(limit int) cli.BashCompleteFunc {
+	return func(c *cli.Context) {
+		word, prev := completionWords(os.Args)
+
+		if strings.HasPrefix(word, "-") {
+			cli.DefaultCompleteWithFlags(c.Command)(c)
+
+			return
+		}
+
+		if limit > 0 && c.NArg() > limit || flagTakesValue(c.Command.Flags, prev) {
+			return
+		}
+
+		printCompletions(c, completionContexts(c))
+	}
+}
+
+// completeWords completes the first argument of a subcommand with one of words.
+func completeWords(words ...string) cli.BashCompleteFunc {
+	return func(c *cli.Context) {
+		if c.NArg() <= 1 {
+			printCompletions(c, words)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestCachedContexts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	cachePath := filepath.Join(dir, "state", "completion.json")
+
+	writeTestFile(t, path, `apiVersion: v1
+kind: Config
+contexts:
+- name: prod
+  context: {cluster: c, user: u}
+- name: dev
+  context: {cluster: c, user: u}
+`)
+
+	got, err := cachedContexts(cachePath, path)
+	if err != nil {
+		t.Fatalf("cachedContexts() error = %v", err)
+	}
+
+	if want := []string{"prod", "dev"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cachedContexts() = %v, want %v", got, want)
+	}
+
+	// An unchanged kubeconfig is not read again
+	cache := readCompletionCache(cachePath)
+	entry := cache[path]
+	entry.Contexts = []string{"cached"}
+	cache[path] = entry
+
+	if err := writeCompletionCache(cachePath, cache); err != nil {
+		t.Fatalf("writeCompletionCache() error = %v", err)
+	}
+
+	if got, _ := cachedContexts(cachePath, path); !reflect.DeepEqual(got, []string{"cached"}) {
+		t.Errorf("cachedContexts() = %v, want the cached contexts", got)
+	}
+
+	writeTestFile(t, path, `apiVersion: v1
+kind: Config
+contexts:
+- name: staging
+  context: {cluster: c, user: u}
+`)
+
+	if got, _ := cachedContexts(cachePath, path); !reflect.DeepEqual(got, []string{"staging"}) {
+		t.Errorf("cachedContexts() = %v, want the contexts of the changed kubeconfig", got)
+	}
+
+	if _, err := cachedContexts(cachePath, filepath.Join(dir, "missing")); err == nil {
+		t.Error("cachedContexts() expected error for a missing kubeconfig")
+	}
+}
+
+func TestCachedContextsList(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	cachePath := filepath.Join(dir, "state", "completion.json")
+
+	writeTestFile(t, first, `apiVersion: v1
+kind: Config
+contexts:
+- name: prod
+  context: {cluster: c, user: u}
+- name: dev
+  context: {cluster: c, user: u}
+`)
+	writeTestFile(t, second, `apiVersion: v1
+kind: Config
+contexts:
+- name: dev
+  context: {cluster: c, user: u}
+- name: staging
+  context: {cluster: c, user: u}
+`)
+
+	pathList := strings.Join([]string{first, filepath.Join(dir, "missing"), second}, string(filepath.ListSeparator))
+
+	if got, want := cachedContextsList(cachePath, pathList), []string{"prod", "dev", "staging"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cachedContextsList() = %v, want %v", got, want)
+	}
+
+	// Each file is cached on its own
+	cache := readCompletionCache(cachePath)
+	if len(cache) != 2 {
+		t.Errorf("completion cache has %d entries, want 2", len(cache))
+	}
+}
+
+func TestCompletionWords(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantWord string
+		wantPrev string
+	}{
+		{args: []string{"ksw", "--generate-bash-completion"}},
+		{args: []string{"ksw", "pro", "--generate-bash-completion"}, wantWord: "pro"},
+		{args: []string{"ksw", "--tag", "", "--generate-bash-completion"}, wantPrev: "--tag"},
+	}
+
+	for _, tt := range tests {
+		word, prev := completionWords(tt.args)
+		if word != tt.wantWord || prev != tt.wantPrev {
+			t.Errorf("completionWords(%v) = %q, %q, want %q, %q", tt.args, word, prev, tt.wantWord, tt.wantPrev)
+		}
+	}
+}
+
+func TestFlagTakesValue(t *testing.T) {
+	flags := []cli.Flag{
+		&cli.StringFlag{Name: "output", Aliases: []string{"o"}},
+		&cli.BoolFlag{Name: "list", Aliases: []string{"l"}},
+	}
+
+	tests := []struct {
+		arg  string
+		want bool
+	}{
+		{arg: "--output", want: true},
+		{arg: "-o", want: true},
+		{arg: "--output=wide", want: false},
+		{arg: "--list", want: false},
+		{arg: "-l", want: false},
+		{arg: "--unknown", want: false},
+		{arg: "output", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := flagTakesValue(flags, tt.arg); got != tt.want {
+			t.Errorf("flagTakesValue(%q) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestCompletionTags(t *testing.T) {
+	contexts := []string{"dev", "minikube", "prod-eu", "prod-us"}
+
+	if got, want := completionTags(testTagConfig, contexts, true), []string{"env", "region", "team"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("completionTags() = %v, want %v", got, want)
+	}
+
+	want := []string{"env=dev", "env=prod", "region=eu", "region=us-east", "team=payments"}
+	if got := completionTags(testTagConfig, contexts, false); !reflect.DeepEqual(got, want) {
+		t.Errorf("completionTags() = %v, want %v", got, want)
+	}
+}
@@ -1,21 +1,104 @@
 package main
 
 import (
+	"cmp"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
-
-	"github.com/ghodss/yaml"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 )
 
 // KswConfig represents the application configuration.
 type KswConfig struct {
-	Kubeconfig KubeconfigConfig `json:"kubeconfig" yaml:"kubeconfig"`
+	Kubeconfig  KubeconfigConfig  `json:"kubeconfig" yaml:"kubeconfig"`
+	Credentials CredentialsConfig `json:"credentials" yaml:"credentials"`
+	Session     SessionConfig     `json:"session" yaml:"session"`
+	Check       CheckConfig       `json:"check" yaml:"check"`
+	Finder      FinderConfig      `json:"finder" yaml:"finder"`
+
+	// Contexts holds per-context settings keyed by a glob pattern matched against context names.
+	Contexts map[string]ContextConfig `json:"contexts" yaml:"contexts"`
+
+	// Context is the context a directory targets. Set in a project .ksw.yaml, it is switched to
+	// automatically by the shell integration once the file is allowed.
+	Context string `json:"context" yaml:"context"`
+	// Namespace is the namespace used with Context.
+	Namespace string `json:"namespace" yaml:"namespace"`
+	// AllowedContexts limits the contexts offered in a project to those matching one of its glob patterns.
+	AllowedContexts []string `json:"allowed_contexts" yaml:"allowed_contexts"`
+
+	// TagPatterns derive tags from context names: every named capture group of a matching
+	// regular expression becomes a tag.
+	TagPatterns []Regexp `json:"tag_patterns" yaml:"tag_patterns"`
+}
+
+// CheckConfig holds configuration related to cluster reachability checks.
+type CheckConfig struct {
+	// Enabled checks the cluster every time a session starts or switches context.
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Strict refuses to enter a context whose cluster is unreachable instead of warning.
+	Strict bool `json:"strict" yaml:"strict"`
+	// Timeout bounds how long a check waits for the API server.
+	Timeout Duration `json:"timeout" yaml:"timeout"`
+}
+
+// FinderConfig holds configuration of the context finder.
+type FinderConfig struct {
+	// Picker is "builtin" for the built-in finder, "fzf" or "sk" to use that picker when it is
+	// installed, or "auto" to use whichever of them is installed.
+	Picker string `json:"picker" yaml:"picker"`
+	// Sort orders contexts by "name", most "recent" use, or their order in the "kubeconfig".
+	Sort string `json:"sort" yaml:"sort"`
+	// Case is the matching mode: "smart", "sensitive" or "insensitive".
+	Case string `json:"case" yaml:"case"`
+	// MultiSelect lets commands that take several contexts, such as export, select them in the finder.
+	MultiSelect bool `json:"multi_select" yaml:"multi_select"`
+	// Header replaces the text shown above the contexts.
+	Header string `json:"header" yaml:"header"`
+}
+
+// ContextConfig holds settings applied to contexts matching a pattern.
+type ContextConfig struct {
+	// Env is exported into the session shell while the context is active.
+	Env EnvVars `json:"env" yaml:"env"`
+	// OnEnter is a shell command run when a session enters the context.
+	OnEnter string `json:"on_enter" yaml:"on_enter"`
+	// OnExit is a shell command run when a session leaves the context.
+	OnExit string `json:"on_exit" yaml:"on_exit"`
+	// Minify overrides kubeconfig.minify for the context when set.
+	Minify *bool `json:"minify,omitempty" yaml:"minify"`
+	// MergeOnExit overrides kubeconfig.merge_on_exit for the context when set.
+	MergeOnExit *MergeOnExitConfig `json:"merge_on_exit,omitempty" yaml:"merge_on_exit"`
+	// Protected asks for confirmation before a session enters the context.
+	Protected bool `json:"protected" yaml:"protected"`
+	// Namespace is the namespace the context starts in, overriding the one in the kubeconfig.
+	Namespace string `json:"namespace" yaml:"namespace"`
+	// Tags label the context for filtering and grouping, such as env: prod.
+	Tags map[string]string `json:"tags" yaml:"tags"`
+}
+
+// SessionConfig holds configuration related to session kubeconfig files.
+type SessionConfig struct {
+	// Dir is where session kubeconfigs are written. Defaults to $XDG_RUNTIME_DIR/ksw.
+	Dir string `json:"dir" yaml:"dir"`
 }
 
 // KubeconfigConfig holds configuration related to kubeconfig minification.
 type KubeconfigConfig struct {
 	Minify      bool              `json:"minify" yaml:"minify"`
 	MergeOnExit MergeOnExitConfig `json:"merge_on_exit" yaml:"merge_on_exit"`
+	Encryption  EncryptionConfig  `json:"encryption" yaml:"encryption"`
+}
+
+// EncryptionConfig holds configuration related to encrypted kubeconfig sources.
+type EncryptionConfig struct {
+	// AgeIdentityFile is the age key file used to decrypt and re-encrypt age encrypted kubeconfigs.
+	// Defaults to $SOPS_AGE_KEY_FILE or ~/.config/sops/age/keys.txt.
+	AgeIdentityFile string `json:"age_identity_file" yaml:"age_identity_file"`
 }
 
 // MergeOnExitConfig holds configuration related to merging on exit.
@@ -23,63 +106,314 @@ type MergeOnExitConfig struct {
 	Enabled bool `json:"enabled" yaml:"enabled"`
 }
 
-var userHomeDir = os.UserHomeDir
+// CredentialsConfig holds configuration related to credential expiry checks.
+type CredentialsConfig struct {
+	// ExpiryWarning is how long before a client certificate or token expires ksw starts warning about it.
+	ExpiryWarning Duration `json:"expiry_warning" yaml:"expiry_warning"`
+	// PrefetchExec runs the exec plugin of the selected context before entering it,
+	// so interactive logins happen before the session starts.
+	PrefetchExec bool `json:"prefetch_exec" yaml:"prefetch_exec"`
+	// PrefetchTimeout bounds how long ksw waits for an exec plugin.
+	PrefetchTimeout Duration `json:"prefetch_timeout" yaml:"prefetch_timeout"`
+}
 
-// loadConfig loads the configuration from ~/.config/ksw/config.yaml or falling back to ~/.ksw.yaml.
-func loadConfig() KswConfig {
-	home, err := userHomeDir()
+// EnvVars maps environment variable names, which must be shell identifiers, to their values.
+type EnvVars map[string]string
+
+// Regexp is a regular expression in RE2 syntax, checked when the config is loaded.
+type Regexp string
+
+// Duration is a time.Duration that is written in config files as a string such as "72h".
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string using time.ParseDuration.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"72h\": %w", err)
+	}
+
+	parsed, err := time.ParseDuration(s)
 	if err != nil {
-		return KswConfig{
-			Kubeconfig: KubeconfigConfig{
-				Minify: false,
-				MergeOnExit: MergeOnExitConfig{
-					Enabled: false,
-				},
-			},
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// MarshalJSON formats the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// matchContextPattern reports whether name matches a glob pattern in which "*" matches
+// any sequence of characters (including "/", which is common in cloud context names)
+// and "?" matches a single character.
+func matchContextPattern(pattern, name string) bool {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+
+	matched, err := regexp.MatchString("^"+expr+"$", name)
+
+	return err == nil && matched
+}
+
+// allowedContexts returns the contexts matching allowed_contexts, or all of them when it is not set.
+func (c KswConfig) allowedContexts(contexts []string) []string {
+	if len(c.AllowedContexts) == 0 {
+		return contexts
+	}
+
+	var allowed []string
+
+	for _, ctx := range contexts {
+		if slices.ContainsFunc(c.AllowedContexts, func(pattern string) bool { return matchContextPattern(pattern, ctx) }) {
+			allowed = append(allowed, ctx)
 		}
 	}
 
-	return loadConfigFromHome(home)
+	return allowed
+}
+
+// patternSpecificity counts the literal characters of a pattern, so exact names outrank wildcards.
+func patternSpecificity(pattern string) int {
+	return len(pattern) - strings.Count(pattern, "*") - strings.Count(pattern, "?")
 }
 
-// loadConfigFromHome loads the configuration relative to a specific home directory.
-func loadConfigFromHome(home string) KswConfig {
-	cfg := KswConfig{
+// contextConfig resolves the settings for contextName by merging every matching entry
+// of the contexts section, from least to most specific pattern.
+func (c KswConfig) contextConfig(contextName string) ContextConfig {
+	var patterns []string
+
+	for pattern := range c.Contexts {
+		if matchContextPattern(pattern, contextName) {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	slices.SortFunc(patterns, func(a, b string) int {
+		return cmp.Or(cmp.Compare(patternSpecificity(a), patternSpecificity(b)), cmp.Compare(a, b))
+	})
+
+	var resolved ContextConfig
+
+	for _, pattern := range patterns {
+		entry := c.Contexts[pattern]
+
+		for k, v := range entry.Env {
+			if resolved.Env == nil {
+				resolved.Env = make(map[string]string)
+			}
+
+			resolved.Env[k] = v
+		}
+
+		if entry.OnEnter != "" {
+			resolved.OnEnter = entry.OnEnter
+		}
+
+		if entry.OnExit != "" {
+			resolved.OnExit = entry.OnExit
+		}
+
+		if entry.Minify != nil {
+			resolved.Minify = entry.Minify
+		}
+
+		if entry.MergeOnExit != nil {
+			resolved.MergeOnExit = entry.MergeOnExit
+		}
+
+		// Any matching pattern protects a context, so a more specific one cannot lift it by accident
+		resolved.Protected = resolved.Protected || entry.Protected
+
+		if entry.Namespace != "" {
+			resolved.Namespace = entry.Namespace
+		}
+
+		for k, v := range entry.Tags {
+			if resolved.Tags == nil {
+				resolved.Tags = make(map[string]string)
+			}
+
+			resolved.Tags[k] = v
+		}
+	}
+
+	return resolved
+}
+
+// minify reports whether the session kubeconfig of contextName is minified,
+// honouring the minify setting of matching contexts entries.
+func (c KswConfig) minify(contextName string) bool {
+	if m := c.contextConfig(contextName).Minify; m != nil {
+		return *m
+	}
+
+	return c.Kubeconfig.Minify
+}
+
+// mergeOnExit reports whether changes made while in contextName are merged back on exit,
+// honouring the merge_on_exit setting of matching contexts entries.
+func (c KswConfig) mergeOnExit(contextName string) bool {
+	if m := c.contextConfig(contextName).MergeOnExit; m != nil {
+		return m.Enabled
+	}
+
+	return c.Kubeconfig.MergeOnExit.Enabled
+}
+
+// hasMergeOnExit reports whether merge on exit is enabled globally or for any contexts entry.
+func (c KswConfig) hasMergeOnExit() bool {
+	if c.Kubeconfig.MergeOnExit.Enabled {
+		return true
+	}
+
+	for _, entry := range c.Contexts {
+		if entry.MergeOnExit != nil && entry.MergeOnExit.Enabled {
+			return true
+		}
+	}
+
+	return false
+}
+
+var userHomeDir = os.UserHomeDir
+
+// defaultConfig returns the configuration used when no config file is present.
+func defaultConfig() KswConfig {
+	return KswConfig{
 		Kubeconfig: KubeconfigConfig{
 			Minify: false,
 			MergeOnExit: MergeOnExitConfig{
 				Enabled: false,
 			},
 		},
+		Check: CheckConfig{
+			Timeout: Duration(5 * time.Second),
+		},
+		Credentials: CredentialsConfig{
+			ExpiryWarning:   Duration(7 * 24 * time.Hour),
+			PrefetchTimeout: Duration(2 * time.Minute),
+		},
+		Finder: FinderConfig{
+			Picker: pickerBuiltin,
+			Sort:   sortName,
+			Case:   caseSmart,
+		},
+	}
+}
+
+// loadConfig loads the configuration from the user config file (see configFilePath), the nearest
+// project .ksw.yaml and KSW_* environment variables. Invalid layers are reported and skipped.
+func loadConfig() KswConfig {
+	resolved, err := loadResolvedConfig()
+	if err != nil && !reportedConfigErrors[err.Error()] {
+		reportedConfigErrors[err.Error()] = true
+
+		for _, line := range strings.Split(err.Error(), "\n") {
+			logf("config error: %s", line)
+		}
+
+		logf("warning: settings with errors are ignored")
 	}
 
-	primaryPath := filepath.Join(home, ".config", "ksw", "config.yaml")
-	fallbackPath := filepath.Join(home, ".ksw.yaml")
+	return resolved.Config
+}
+
+// loadResolvedConfig resolves the configuration layers for the current user and directory.
+func loadResolvedConfig() (resolvedConfig, error) {
+	home, err := userHomeDir()
+	if err != nil {
+		home = ""
+	}
 
-	var configBytes []byte
+	dir, err := workingDir()
+	if err != nil {
+		dir = ""
+	}
 
-	var readErr error
+	return resolveConfig(home, dir, os.LookupEnv)
+}
 
-	if _, err := os.Stat(primaryPath); err == nil {
-		configBytes, readErr = os.ReadFile(primaryPath)
-	} else if _, err := os.Stat(fallbackPath); err == nil {
-		configBytes, readErr = os.ReadFile(fallbackPath)
-	} else {
-		return cfg
+// configFilePath returns the user config file: the file named by KSW_CONFIG (also set by
+// --config), or the first of $XDG_CONFIG_HOME/ksw/config.yaml, ~/.config/ksw/config.yaml and
+// ~/.ksw.yaml that exists. It returns "" when there is none. A file named by KSW_CONFIG is
+// returned even when it is missing, so that reading it reports the error.
+func configFilePath(home string, lookupEnv func(string) (string, bool)) string {
+	if path, ok := lookupEnv("KSW_CONFIG"); ok && path != "" {
+		return path
 	}
 
-	if readErr != nil {
-		return cfg
+	for _, path := range configFileCandidates(home, lookupEnv) {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
 	}
 
-	var parsedCfg KswConfig
+	return ""
+}
+
+// configFileCandidates returns the paths a user config file is looked for at when KSW_CONFIG
+// is not set, in order of preference.
+func configFileCandidates(home string, lookupEnv func(string) (string, bool)) []string {
+	var candidates []string
 
-	parsedCfg.Kubeconfig.Minify = false
-	parsedCfg.Kubeconfig.MergeOnExit.Enabled = false
+	if dir, ok := lookupEnv("XDG_CONFIG_HOME"); ok && filepath.IsAbs(dir) {
+		candidates = append(candidates, filepath.Join(dir, "ksw", "config.yaml"))
+	}
+
+	if home != "" {
+		candidates = append(candidates,
+			filepath.Join(home, ".config", "ksw", "config.yaml"),
+			filepath.Join(home, ".ksw.yaml"),
+		)
+	}
 
-	if err := yaml.Unmarshal(configBytes, &parsedCfg); err != nil {
-		return cfg
+	return candidates
+}
+
+// userConfigPaths returns every path read as a user config file rather than a project config,
+// whether or not it is the one in use: the file named by KSW_CONFIG and all the candidates.
+func userConfigPaths(home string, lookupEnv func(string) (string, bool)) []string {
+	paths := configFileCandidates(home, lookupEnv)
+
+	if path, ok := lookupEnv("KSW_CONFIG"); ok && path != "" {
+		paths = append(paths, path)
+	}
+
+	for i, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			paths[i] = abs
+		}
+	}
+
+	return paths
+}
+
+// newConfigFilePath returns where a user config file is created when none exists yet,
+// $XDG_CONFIG_HOME/ksw/config.yaml or ~/.config/ksw/config.yaml.
+func newConfigFilePath(home string, lookupEnv func(string) (string, bool)) string {
+	if dir, ok := lookupEnv("XDG_CONFIG_HOME"); ok && filepath.IsAbs(dir) {
+		return filepath.Join(dir, "ksw", "config.yaml")
+	}
+
+	return filepath.Join(home, ".config", "ksw", "config.yaml")
+}
+
+// reportedConfigErrors remembers which config errors were already reported,
+// since the config is loaded several times while entering a session.
+var reportedConfigErrors = make(map[string]bool)
+
+// readConfigFile reads and strictly parses a config file.
+func readConfigFile(path string) (KswConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return defaultConfig(), err
 	}
 
-	return parsedCfg
+	return parseConfig(path, b)
 }
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ghodss/yaml"
 	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
@@ -165,6 +167,164 @@ func TestLoadConfig(t *testing.T) {
 	}
 }
 
+func TestLoadConfigExpiryWarning(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    time.Duration
+	}{
+		{
+			name: "default",
+			want: 7 * 24 * time.Hour,
+		},
+		{
+			name:    "configured",
+			content: "credentials:\n  expiry_warning: 72h\n",
+			want:    72 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			if tt.content != "" {
+				if err := os.WriteFile(filepath.Join(home, ".ksw.yaml"), []byte(tt.content), 0600); err != nil {
+					t.Fatalf("Failed to write config file: %v", err)
+				}
+			}
+
+			resolved, err := resolveConfig(home, "", func(string) (string, bool) { return "", false })
+			if err != nil {
+				t.Fatalf("resolveConfig() error = %v", err)
+			}
+
+			if got := time.Duration(resolved.Config.Credentials.ExpiryWarning); got != tt.want {
+				t.Errorf("resolveConfig() ExpiryWarning = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchContextPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "prod", name: "prod", want: true},
+		{pattern: "prod", name: "prod-eu", want: false},
+		{pattern: "prod-*", name: "prod-eu", want: true},
+		{pattern: "*", name: "arn:aws:eks:eu-west-1:123456789012:cluster/prod", want: true},
+		{pattern: "arn:aws:eks:*:cluster/prod", name: "arn:aws:eks:eu-west-1:123456789012:cluster/prod", want: true},
+		{pattern: "dev-?", name: "dev-1", want: true},
+		{pattern: "dev-?", name: "dev-10", want: false},
+		{pattern: "a.b", name: "axb", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := matchContextPattern(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchContextPattern(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestContextConfig(t *testing.T) {
+	content := []byte(`contexts:
+  "*":
+    env:
+      HELM_NAMESPACE: default
+  "prod-*":
+    env:
+      AWS_PROFILE: prod
+    on_enter: echo entering prod
+    on_exit: echo leaving prod
+  prod-eu:
+    env:
+      HELM_NAMESPACE: payments
+    on_enter: echo entering prod-eu
+`)
+	cfg, err := parseConfig("config.yaml", content)
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+
+	got := cfg.contextConfig("prod-eu")
+	want := ContextConfig{
+		Env:     map[string]string{"AWS_PROFILE": "prod", "HELM_NAMESPACE": "payments"},
+		OnEnter: "echo entering prod-eu",
+		OnExit:  "echo leaving prod",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("contextConfig(prod-eu) = %+v, want %+v", got, want)
+	}
+
+	got = cfg.contextConfig("dev")
+	want = ContextConfig{Env: map[string]string{"HELM_NAMESPACE": "default"}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("contextConfig(dev) = %+v, want %+v", got, want)
+	}
+}
+
+func TestContextOverrides(t *testing.T) {
+	content := []byte(`kubeconfig:
+  minify: true
+contexts:
+  "prod-*":
+    minify: false
+    merge_on_exit:
+      enabled: true
+    protected: true
+    namespace: default
+  prod-eu:
+    protected: false
+    namespace: payments
+  dev:
+    merge_on_exit:
+      enabled: false
+`)
+	cfg, err := parseConfig("config.yaml", content)
+	if err != nil {
+		t.Fatalf("parseConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		context       string
+		wantMinify    bool
+		wantMerge     bool
+		wantProtected bool
+		wantNamespace string
+	}{
+		{context: "prod-us", wantMinify: false, wantMerge: true, wantProtected: true, wantNamespace: "default"},
+		{context: "prod-eu", wantMinify: false, wantMerge: true, wantProtected: true, wantNamespace: "payments"},
+		{context: "dev", wantMinify: true, wantMerge: false},
+		{context: "staging", wantMinify: true, wantMerge: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.context, func(t *testing.T) {
+			if got := cfg.minify(tt.context); got != tt.wantMinify {
+				t.Errorf("minify() = %v, want %v", got, tt.wantMinify)
+			}
+
+			if got := cfg.mergeOnExit(tt.context); got != tt.wantMerge {
+				t.Errorf("mergeOnExit() = %v, want %v", got, tt.wantMerge)
+			}
+
+			ctxCfg := cfg.contextConfig(tt.context)
+
+			if ctxCfg.Protected != tt.wantProtected || ctxCfg.Namespace != tt.wantNamespace {
+				t.Errorf("contextConfig() = %+v", ctxCfg)
+			}
+		})
+	}
+
+	if !cfg.hasMergeOnExit() {
+		t.Errorf("hasMergeOnExit() = false, want true")
+	}
+}
+
 func TestGenerateKubeconfig_MinifyToggle(t *testing.T) {
 	origUserHomeDir := userHomeDir
 
@@ -282,3 +442,26 @@ users:
 		}
 	})
 }
+
+func TestAllowedContexts(t *testing.T) {
+	contexts := []string{"dev", "prod", "staging-eu", "staging-us"}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		want    []string
+	}{
+		{name: "unset", want: contexts},
+		{name: "patterns", allowed: []string{"staging-*", "dev"}, want: []string{"dev", "staging-eu", "staging-us"}},
+		{name: "no match", allowed: []string{"qa"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := KswConfig{AllowedContexts: tt.allowed}
+			if got := cfg.allowedContexts(contexts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allowedContexts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
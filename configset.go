@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// configKeyPath splits a dotted key into the YAML keys it names below t and returns the type
+// of the value there. Keys of maps such as context patterns may themselves contain dots.
+func configKeyPath(t reflect.Type, key string) ([]string, reflect.Type, error) {
+	path, leaf, ok := resolveKeyPath(t, strings.Split(key, "."))
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown key %q", key)
+	}
+
+	return path, leaf, nil
+}
+
+func resolveKeyPath(t reflect.Type, segments []string) ([]string, reflect.Type, bool) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if len(segments) == 0 {
+		return nil, t, true
+	}
+
+	if t == durationType {
+		return nil, nil, false
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if jsonFieldName(t.Field(i)) != segments[0] || segments[0] == "" {
+				continue
+			}
+
+			path, leaf, ok := resolveKeyPath(t.Field(i).Type, segments[1:])
+			if !ok {
+				return nil, nil, false
+			}
+
+			return append([]string{segments[0]}, path...), leaf, true
+		}
+	case reflect.Map:
+		elem := t.Elem()
+
+		if elem.Kind() != reflect.Struct || elem == durationType {
+			return []string{strings.Join(segments, ".")}, elem, true
+		}
+
+		for i := 1; i < len(segments); i++ {
+			path, leaf, ok := resolveKeyPath(elem, segments[i:])
+			if ok {
+				return append([]string{strings.Join(segments[:i], ".")}, path...), leaf, true
+			}
+		}
+	}
+
+	return nil, nil, false
+}
+
+// configScalarNode returns the YAML node holding s as a value of the setting type t.
+func configScalarNode(t reflect.Type, s string) (*yamlv3.Node, error) {
+	if t.Kind() == reflect.Struct && t != durationType || t.Kind() == reflect.Map || t.Kind() == reflect.Slice {
+		return nil, fmt.Errorf("not a single setting, set one of its keys instead")
+	}
+
+	v, err := parseConfigValue(t, s)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: s}
+
+	switch {
+	case t == durationType:
+	case t.Kind() == reflect.Bool:
+		node.Tag, node.Value = "!!bool", strconv.FormatBool(v.Bool())
+	case t.Kind() == reflect.Int || t.Kind() == reflect.Int64:
+		node.Tag, node.Value = "!!int", strconv.FormatInt(v.Int(), 10)
+	}
+
+	return node, nil
+}
+
+// setConfigNode sets the value at path in a YAML document, creating the mappings above it.
+// A value that is already there is replaced in place so that its comments are kept.
+func setConfigNode(doc *yamlv3.Node, path []string, value *yamlv3.Node) {
+	if len(doc.Content) == 0 {
+		doc.Content = []*yamlv3.Node{{Kind: yamlv3.MappingNode, Tag: "!!map"}}
+	}
+
+	node := doc.Content[0]
+
+	for i, key := range path {
+		if node.Kind != yamlv3.MappingNode {
+			*node = yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map", HeadComment: node.HeadComment, LineComment: node.LineComment}
+		}
+
+		var child *yamlv3.Node
+
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				child = node.Content[j+1]
+
+				break
+			}
+		}
+
+		if child == nil {
+			child = &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: key}, child)
+		}
+
+		if i == len(path)-1 {
+			child.Kind, child.Tag, child.Value, child.Style = value.Kind, value.Tag, value.Value, value.Style
+			child.Content, child.Alias, child.Anchor = nil, nil, ""
+		}
+
+		node = child
+	}
+}
+
+// unsetConfigNode removes the value at path from a mapping node, together with mappings
+// that are left empty. It reports whether the value was there.
+func unsetConfigNode(node *yamlv3.Node, path []string) bool {
+	if node.Kind != yamlv3.MappingNode {
+		return false
+	}
+
+	for j := 0; j+1 < len(node.Content); j += 2 {
+		if node.Content[j].Value != path[0] {
+			continue
+		}
+
+		child := node.Content[j+1]
+
+		if len(path) > 1 {
+			if !unsetConfigNode(child, path[1:]) {
+				return false
+			}
+
+			if len(child.Content) > 0 {
+				return true
+			}
+		}
+
+		node.Content = append(node.Content[:j], node.Content[j+2:]...)
+
+		return true
+	}
+
+	return false
+}
+
+// editConfigFile applies edit to the YAML document of a config file, creating the file when it
+// is missing, and only writes it back when the result is still a valid config.
+func editConfigFile(path string, edit func(doc *yamlv3.Node) error) error {
+	b, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(b, &doc); err != nil {
+		return yamlSyntaxError(path, err)
+	}
+
+	if doc.Kind == 0 {
+		doc.Kind = yamlv3.DocumentNode
+	}
+
+	if err := edit(&doc); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	enc := yamlv3.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	if _, err := parseConfig(path, buf.Bytes()); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// configEditPath returns the config file changed by 'ksw config set' and 'unset': the --file flag,
+// the user config file, or the path a new user config file is created at.
+func configEditPath(c *cli.Context) (string, error) {
+	if path := c.String("file"); path != "" {
+		return path, nil
+	}
+
+	home, err := userHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	if path := configFilePath(home, os.LookupEnv); path != "" {
+		return path, nil
+	}
+
+	return newConfigFilePath(home, os.LookupEnv), nil
+}
+
+// configGetAction prints the effective value of a setting, or of every setting below a section.
+func configGetAction(c *cli.Context) error {
+	key := c.Args().First()
+	if key == "" || c.Args().Len() > 1 {
+		return fmt.Errorf("usage: ksw config get <key>")
+	}
+
+	if _, _, err := configKeyPath(reflect.TypeFor[KswConfig](), key); err != nil {
+		return err
+	}
+
+	cfg := loadConfig()
+	if !c.Bool("show-secrets") {
+		cfg = redactKswConfig(cfg)
+	}
+
+	var values []configValue
+
+	for _, v := range configValues("", reflect.ValueOf(cfg)) {
+		if v.Key == key {
+			fmt.Println(v.Value)
+
+			return nil
+		}
+
+		if strings.HasPrefix(v.Key, key+".") {
+			values = append(values, v)
+		}
+	}
+
+	if len(values) == 0 {
+		return fmt.Errorf("%s is not set", key)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	for _, v := range values {
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", v.Key, v.Value)
+	}
+
+	return w.Flush()
+}
+
+// configSetAction sets a setting in the config file after checking it against its type.
+func configSetAction(c *cli.Context) error {
+	if c.Args().Len() != 2 {
+		return fmt.Errorf("usage: ksw config set <key> <value>")
+	}
+
+	key, value := c.Args().Get(0), c.Args().Get(1)
+
+	keyPath, t, err := configKeyPath(reflect.TypeFor[KswConfig](), key)
+	if err != nil {
+		return err
+	}
+
+	node, err := configScalarNode(t, value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	path, err := configEditPath(c)
+	if err != nil {
+		return err
+	}
+
+	if err := editConfigFile(path, func(doc *yamlv3.Node) error {
+		setConfigNode(doc, keyPath, node)
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	logf("set %s to %s in %s", key, node.Value, path)
+
+	return nil
+}
+
+// configUnsetAction removes a setting from the config file, so that it falls back to the
+// value of the layers below.
+func configUnsetAction(c *cli.Context) error {
+	key := c.Args().First()
+	if key == "" || c.Args().Len() > 1 {
+		return fmt.Errorf("usage: ksw config unset <key>")
+	}
+
+	keyPath, _, err := configKeyPath(reflect.TypeFor[KswConfig](), key)
+	if err != nil {
+		return err
+	}
+
+	path, err := configEditPath(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s is not set in %s", key, path)
+	}
+
+	if err := editConfigFile(path, func(doc *yamlv3.Node) error {
+		if len(doc.Content) == 0 || !unsetConfigNode(doc.Content[0], keyPath) {
+			return fmt.Errorf("%s is not set in %s", key, path)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	logf("unset %s in %s", key, path)
+
+	return nil
+}
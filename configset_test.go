@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+func TestConfigKeyPath(t *testing.T) {
+	tests := []struct {
+		key      string
+		wantPath []string
+		wantType reflect.Type
+		wantErr  bool
+	}{
+		{key: "kubeconfig.minify", wantPath: []string{"kubeconfig", "minify"}, wantType: reflect.TypeFor[bool]()},
+		{key: "check.timeout", wantPath: []string{"check", "timeout"}, wantType: durationType},
+		{key: "check", wantPath: []string{"check"}, wantType: reflect.TypeFor[CheckConfig]()},
+		{key: "contexts.prod.eu.on_enter", wantPath: []string{"contexts", "prod.eu", "on_enter"}, wantType: reflect.TypeFor[string]()},
+		{key: "contexts.prod-*.env.AWS_PROFILE", wantPath: []string{"contexts", "prod-*", "env", "AWS_PROFILE"}, wantType: reflect.TypeFor[string]()},
+		{key: "contexts.prod.minify", wantPath: []string{"contexts", "prod", "minify"}, wantType: reflect.TypeFor[bool]()},
+		{key: "contexts.prod.merge_on_exit.enabled", wantPath: []string{"contexts", "prod", "merge_on_exit", "enabled"}, wantType: reflect.TypeFor[bool]()},
+		{key: "check.timout", wantErr: true},
+		{key: "contexts.prod", wantErr: true},
+		{key: "kubeconfig.minify.enabled", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			path, typ, err := configKeyPath(reflect.TypeFor[KswConfig](), tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("configKeyPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(path, tt.wantPath) || typ != tt.wantType {
+				t.Errorf("configKeyPath() = %v, %v, want %v, %v", path, typ, tt.wantPath, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestConfigScalarNode(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     reflect.Type
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "bool", typ: reflect.TypeFor[bool](), value: "1", want: "true"},
+		{name: "invalid bool", typ: reflect.TypeFor[bool](), value: "yes", wantErr: true},
+		{name: "duration", typ: durationType, value: "90s", want: "90s"},
+		{name: "invalid duration", typ: durationType, value: "soon", wantErr: true},
+		{name: "string", typ: reflect.TypeFor[string](), value: "true", want: "true"},
+		{name: "section", typ: reflect.TypeFor[CheckConfig](), value: "x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := configScalarNode(tt.typ, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("configScalarNode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && node.Value != tt.want {
+				t.Errorf("configScalarNode() = %q, want %q", node.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	writeTestFile(t, path, `# ksw settings
+kubeconfig:
+  minify: false # keep every context
+check:
+  timeout: 5s
+`)
+
+	set := func(key, value string) {
+		t.Helper()
+
+		keyPath, typ, err := configKeyPath(reflect.TypeFor[KswConfig](), key)
+		if err != nil {
+			t.Fatalf("configKeyPath(%s) error = %v", key, err)
+		}
+
+		node, err := configScalarNode(typ, value)
+		if err != nil {
+			t.Fatalf("configScalarNode(%s) error = %v", value, err)
+		}
+
+		if err := editConfigFile(path, func(doc *yamlv3.Node) error {
+			setConfigNode(doc, keyPath, node)
+
+			return nil
+		}); err != nil {
+			t.Fatalf("editConfigFile() error = %v", err)
+		}
+	}
+
+	set("kubeconfig.minify", "true")
+	set("contexts.prod-*.env.AWS_PROFILE", "true")
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	for _, want := range []string{"# ksw settings", "minify: true # keep every context", `AWS_PROFILE: "true"`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("config = %s, want containing %q", b, want)
+		}
+	}
+
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("readConfigFile() error = %v", err)
+	}
+
+	if !cfg.Kubeconfig.Minify || cfg.Contexts["prod-*"].Env["AWS_PROFILE"] != "true" {
+		t.Errorf("readConfigFile() = %+v", cfg)
+	}
+
+	if err := editConfigFile(path, func(doc *yamlv3.Node) error {
+		if !unsetConfigNode(doc.Content[0], []string{"contexts", "prod-*", "env", "AWS_PROFILE"}) {
+			t.Errorf("unsetConfigNode() = false, want true")
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatalf("editConfigFile() error = %v", err)
+	}
+
+	b, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	if strings.Contains(string(b), "contexts") {
+		t.Errorf("emptied mappings should be removed, got %s", b)
+	}
+}
+
+func TestEditConfigFileCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ksw", "config.yaml")
+
+	if err := editConfigFile(path, func(doc *yamlv3.Node) error {
+		setConfigNode(doc, []string{"check", "enabled"}, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!bool", Value: "true"})
+
+		return nil
+	}); err != nil {
+		t.Fatalf("editConfigFile() error = %v", err)
+	}
+
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("readConfigFile() error = %v", err)
+	}
+
+	if !cfg.Check.Enabled {
+		t.Errorf("check.enabled was not set")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// clientCertificateExpiry returns the expiry time of the first certificate in PEM encoded data.
+func clientCertificateExpiry(data []byte) (time.Time, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, fmt.Errorf("no PEM certificate found")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return cert.NotAfter, nil
+}
+
+// tokenExpiry returns the "exp" claim of a JWT bearer token. The signature is not verified.
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("token is not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid JWT payload: %w", err)
+	}
+
+	var claims struct {
+		Exp *float64 `json:"exp"`
+	}
+
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("invalid JWT claims: %w", err)
+	}
+
+	if claims.Exp == nil {
+		return time.Time{}, fmt.Errorf("JWT has no exp claim")
+	}
+
+	return time.Unix(int64(*claims.Exp), 0).UTC(), nil
+}
+
+// credentialExpiry returns the earliest expiry of the client certificate and bearer token
+// embedded in auth. It returns false when neither carries an expiry.
+func credentialExpiry(auth apiv1.AuthInfo) (time.Time, bool) {
+	var (
+		earliest time.Time
+		found    bool
+	)
+
+	consider := func(t time.Time, err error) {
+		if err == nil && (!found || t.Before(earliest)) {
+			earliest = t
+			found = true
+		}
+	}
+
+	if len(auth.ClientCertificateData) > 0 {
+		consider(clientCertificateExpiry(auth.ClientCertificateData))
+	}
+
+	if auth.Token != "" {
+		consider(tokenExpiry(auth.Token))
+	}
+
+	return earliest, found
+}
+
+// contextCredentialExpiry returns the credential expiry of the user referenced by contextName.
+func contextCredentialExpiry(config apiv1.Config, contextName string) (time.Time, bool) {
+	ctx, ok := contextsMap(config.Contexts)[contextName]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	user, ok := usersMap(config.AuthInfos)[ctx.AuthInfo]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return credentialExpiry(user)
+}
+
+// expiryWarning returns a warning when expiry has passed or falls within window of now,
+// and an empty string otherwise.
+func expiryWarning(contextName string, expiry time.Time, window time.Duration, now time.Time) string {
+	switch {
+	case !expiry.After(now):
+		return fmt.Sprintf("credentials for context %s expired at %s", contextName, expiry.Local().Format(time.RFC3339))
+	case expiry.Sub(now) <= window:
+		return fmt.Sprintf("credentials for context %s expire in %s (at %s)",
+			contextName, expiry.Sub(now).Round(time.Minute), expiry.Local().Format(time.RFC3339))
+	default:
+		return ""
+	}
+}
+
+// formatExpiry renders an expiry time for listings, marking it when already expired.
+func formatExpiry(expiry time.Time, now time.Time) string {
+	s := expiry.Local().Format("2006-01-02 15:04")
+	if !expiry.After(now) {
+		s += " (expired)"
+	}
+
+	return s
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// newTestCertificate returns a PEM encoded self-signed certificate that expires at notAfter.
+func newTestCertificate(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ksw-test"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestClientCertificateExpiry(t *testing.T) {
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	got, err := clientCertificateExpiry(newTestCertificate(t, notAfter))
+	if err != nil {
+		t.Fatalf("clientCertificateExpiry() error = %v", err)
+	}
+
+	if !got.Equal(notAfter) {
+		t.Errorf("clientCertificateExpiry() = %v, want %v", got, notAfter)
+	}
+
+	if _, err := clientCertificateExpiry([]byte("not a certificate")); err == nil {
+		t.Error("clientCertificateExpiry() expected error for invalid data")
+	}
+}
+
+// newTestJWT returns an unsigned JWT whose payload is the given JSON claims.
+func newTestJWT(claims string) string {
+	enc := base64.RawURLEncoding
+
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:  "jwt with exp",
+			token: newTestJWT(`{"sub":"me","exp":1893456000}`),
+			want:  time.Unix(1893456000, 0).UTC(),
+		},
+		{
+			name:    "jwt without exp",
+			token:   newTestJWT(`{"sub":"me"}`),
+			wantErr: true,
+		},
+		{
+			name:    "opaque token",
+			token:   "abcdef.0123456789abcdef",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokenExpiry(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("tokenExpiry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("tokenExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentialExpiry(t *testing.T) {
+	certExpiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tokenExp := time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	auth := apiv1.AuthInfo{
+		ClientCertificateData: newTestCertificate(t, certExpiry),
+		Token:                 newTestJWT(fmt.Sprintf(`{"exp":%d}`, tokenExp.Unix())),
+	}
+
+	got, ok := credentialExpiry(auth)
+	if !ok || !got.Equal(tokenExp) {
+		t.Errorf("credentialExpiry() = %v, %v, want %v, true", got, ok, tokenExp)
+	}
+
+	if _, ok := credentialExpiry(apiv1.AuthInfo{Token: "opaque"}); ok {
+		t.Error("credentialExpiry() expected no expiry for opaque token")
+	}
+}
+
+func TestExpiryWarning(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	window := 24 * time.Hour
+
+	if msg := expiryWarning("prod", now.Add(-time.Minute), window, now); !strings.Contains(msg, "expired") {
+		t.Errorf("expiryWarning() for expired credentials = %q", msg)
+	}
+
+	if msg := expiryWarning("prod", now.Add(2*time.Hour), window, now); !strings.Contains(msg, "expire in 2h0m0s") {
+		t.Errorf("expiryWarning() for expiring credentials = %q", msg)
+	}
+
+	if msg := expiryWarning("prod", now.Add(48*time.Hour), window, now); msg != "" {
+		t.Errorf("expiryWarning() outside window = %q, want empty", msg)
+	}
+}
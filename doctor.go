@@ -0,0 +1,260 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// DoctorSeverity classifies how serious a doctor finding is.
+type DoctorSeverity string
+
+const (
+	SeverityError   DoctorSeverity = "error"
+	SeverityWarning DoctorSeverity = "warning"
+)
+
+// Exit codes returned by the doctor command.
+const (
+	doctorExitErrors   = 1
+	doctorExitWarnings = 2
+)
+
+// DoctorFinding is a single problem detected in a kubeconfig source.
+type DoctorFinding struct {
+	Severity DoctorSeverity `json:"severity"`
+	Source   string         `json:"source"`
+	Type     ChangeItemType `json:"type,omitempty"`
+	Name     string         `json:"name,omitempty"`
+	Message  string         `json:"message"`
+}
+
+// String returns a single line description of the finding.
+func (f DoctorFinding) String() string {
+	severity := strings.ToUpper(string(f.Severity))
+	if f.Type == "" {
+		return fmt.Sprintf("%-7s %s: %s", severity, f.Source, f.Message)
+	}
+
+	return fmt.Sprintf("%-7s %s: %s %q: %s", severity, f.Source, f.Type, f.Name, f.Message)
+}
+
+var lookPath = exec.LookPath
+
+// diagnoseKubeconfigs loads every kubeconfig source and reports problems found in them.
+// Sources that cannot be read or parsed are reported as findings rather than errors.
+func diagnoseKubeconfigs(paths []string, now time.Time) []DoctorFinding {
+	var findings []DoctorFinding
+
+	contextSources := make(map[string][]string)
+	clusterSources := make(map[string][]string)
+	userSources := make(map[string][]string)
+
+	var merged apiv1.Config
+
+	for _, path := range paths {
+		config, err := readKubeconfig(path)
+		if err != nil {
+			findings = append(findings, DoctorFinding{
+				Severity: SeverityError,
+				Source:   path,
+				Message:  fmt.Sprintf("cannot load kubeconfig: %v", err),
+			})
+
+			continue
+		}
+
+		for _, x := range config.Contexts {
+			contextSources[x.Name] = append(contextSources[x.Name], path)
+		}
+
+		for _, x := range config.Clusters {
+			clusterSources[x.Name] = append(clusterSources[x.Name], path)
+			findings = append(findings, diagnoseCluster(path, x)...)
+		}
+
+		for _, x := range config.AuthInfos {
+			userSources[x.Name] = append(userSources[x.Name], path)
+			findings = append(findings, diagnoseUser(path, x, now)...)
+		}
+
+		merged.Contexts = append(merged.Contexts, config.Contexts...)
+		merged.Clusters = append(merged.Clusters, config.Clusters...)
+		merged.AuthInfos = append(merged.AuthInfos, config.AuthInfos...)
+	}
+
+	findings = append(findings, duplicateFindings(ChangeContext, contextSources)...)
+	findings = append(findings, duplicateFindings(ChangeCluster, clusterSources)...)
+	findings = append(findings, duplicateFindings(ChangeUser, userSources)...)
+
+	for _, r := range checkReferences(merged) {
+		findings = append(findings, DoctorFinding{
+			Severity: SeverityError,
+			Source:   contextSources[r.Context][0],
+			Type:     ChangeContext,
+			Name:     r.Context,
+			Message:  fmt.Sprintf("references missing %s %q", strings.ToLower(string(r.Type)), r.Name),
+		})
+	}
+
+	slices.SortStableFunc(findings, func(a, b DoctorFinding) int {
+		return cmp.Or(cmp.Compare(a.Source, b.Source), cmp.Compare(a.Type, b.Type), cmp.Compare(a.Name, b.Name))
+	})
+
+	return findings
+}
+
+// duplicateFindings reports names defined in more than one source. kubectl only honours
+// the first definition, so later ones are silently ignored.
+func duplicateFindings(typ ChangeItemType, sources map[string][]string) []DoctorFinding {
+	var findings []DoctorFinding
+
+	for name, paths := range sources {
+		if len(paths) < 2 {
+			continue
+		}
+
+		findings = append(findings, DoctorFinding{
+			Severity: SeverityWarning,
+			Source:   paths[0],
+			Type:     typ,
+			Name:     name,
+			Message:  fmt.Sprintf("also defined in %s; only the first definition is used", strings.Join(paths[1:], ", ")),
+		})
+	}
+
+	return findings
+}
+
+// resolveKubeconfigPath resolves a file reference relative to the kubeconfig that contains it.
+func resolveKubeconfigPath(source, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(filepath.Dir(source), path)
+}
+
+func diagnoseCluster(source string, cluster apiv1.NamedCluster) []DoctorFinding {
+	var findings []DoctorFinding
+
+	if ca := cluster.Cluster.CertificateAuthority; ca != "" {
+		if _, err := os.ReadFile(resolveKubeconfigPath(source, ca)); err != nil {
+			findings = append(findings, DoctorFinding{
+				Severity: SeverityError,
+				Source:   source,
+				Type:     ChangeCluster,
+				Name:     cluster.Name,
+				Message:  fmt.Sprintf("unreadable certificate-authority: %v", err),
+			})
+		}
+	}
+
+	if cluster.Cluster.InsecureSkipTLSVerify {
+		findings = append(findings, DoctorFinding{
+			Severity: SeverityWarning,
+			Source:   source,
+			Type:     ChangeCluster,
+			Name:     cluster.Name,
+			Message:  "insecure-skip-tls-verify is enabled",
+		})
+	}
+
+	return findings
+}
+
+func diagnoseUser(source string, user apiv1.NamedAuthInfo, now time.Time) []DoctorFinding {
+	var findings []DoctorFinding
+
+	if data := user.AuthInfo.ClientCertificateData; len(data) > 0 {
+		expiry, err := clientCertificateExpiry(data)
+
+		switch {
+		case err != nil:
+			findings = append(findings, DoctorFinding{
+				Severity: SeverityError,
+				Source:   source,
+				Type:     ChangeUser,
+				Name:     user.Name,
+				Message:  fmt.Sprintf("invalid client-certificate-data: %v", err),
+			})
+		case expiry.Before(now):
+			findings = append(findings, DoctorFinding{
+				Severity: SeverityError,
+				Source:   source,
+				Type:     ChangeUser,
+				Name:     user.Name,
+				Message:  fmt.Sprintf("client certificate expired at %s", expiry.Format(time.RFC3339)),
+			})
+		}
+	}
+
+	if plugin := user.AuthInfo.Exec; plugin != nil && plugin.Command != "" {
+		if _, err := lookPath(plugin.Command); err != nil {
+			findings = append(findings, DoctorFinding{
+				Severity: SeverityError,
+				Source:   source,
+				Type:     ChangeUser,
+				Name:     user.Name,
+				Message:  fmt.Sprintf("exec plugin command %q not found in PATH", plugin.Command),
+			})
+		}
+	}
+
+	return findings
+}
+
+func doctorAction(c *cli.Context) error {
+	paths := filepath.SplitList(getOriginalKubeconfigPath())
+	findings := diagnoseKubeconfigs(paths, time.Now())
+
+	switch c.String("output") {
+	case "json":
+		if findings == nil {
+			findings = []DoctorFinding{}
+		}
+
+		b, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(b))
+	case "", "text":
+		for _, f := range findings {
+			fmt.Println(f)
+		}
+	default:
+		return fmt.Errorf("unsupported output format %q", c.String("output"))
+	}
+
+	errorCount := 0
+
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			errorCount++
+		}
+	}
+
+	warningCount := len(findings) - errorCount
+
+	logf("checked %d kubeconfig source(s): %d error(s), %d warning(s)", len(paths), errorCount, warningCount)
+
+	switch {
+	case errorCount > 0:
+		return cli.Exit("", doctorExitErrors)
+	case warningCount > 0:
+		return cli.Exit("", doctorExitWarnings)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+func writeTestKubeconfig(t *testing.T, path string, config apiv1.Config) {
+	t.Helper()
+
+	b, err := yaml.Marshal(config)
+	if err != nil {
+		t.Fatalf("Failed to marshal kubeconfig: %v", err)
+	}
+
+	if err := os.WriteFile(path, b, 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+}
+
+func TestDiagnoseKubeconfigs(t *testing.T) {
+	origLookPath := lookPath
+
+	defer func() {
+		lookPath = origLookPath
+	}()
+
+	lookPath = func(file string) (string, error) {
+		if file == "aws" {
+			return "/usr/bin/aws", nil
+		}
+
+		return "", errors.New("not found")
+	}
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tmpDir := t.TempDir()
+
+	first := filepath.Join(tmpDir, "first")
+	second := filepath.Join(tmpDir, "second")
+	missing := filepath.Join(tmpDir, "missing")
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "ca.crt"), []byte("ca"), 0600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	writeTestKubeconfig(t, first, apiv1.Config{
+		Contexts: []apiv1.NamedContext{
+			{Name: "good", Context: apiv1.Context{Cluster: "good", AuthInfo: "good"}},
+			{Name: "dangling", Context: apiv1.Context{Cluster: "nowhere", AuthInfo: "good"}},
+		},
+		Clusters: []apiv1.NamedCluster{
+			{Name: "good", Cluster: apiv1.Cluster{Server: "https://good.example.com", CertificateAuthority: "ca.crt"}},
+			{Name: "bad-ca", Cluster: apiv1.Cluster{Server: "https://bad.example.com", CertificateAuthority: "missing.crt"}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "good", AuthInfo: apiv1.AuthInfo{Exec: &apiv1.ExecConfig{Command: "aws"}}},
+			{Name: "expired", AuthInfo: apiv1.AuthInfo{ClientCertificateData: newTestCertificate(t, now.Add(-time.Hour))}},
+			{Name: "valid-cert", AuthInfo: apiv1.AuthInfo{ClientCertificateData: newTestCertificate(t, now.Add(time.Hour))}},
+		},
+	})
+
+	writeTestKubeconfig(t, second, apiv1.Config{
+		Contexts: []apiv1.NamedContext{
+			{Name: "good", Context: apiv1.Context{Cluster: "insecure", AuthInfo: "no-plugin"}},
+		},
+		Clusters: []apiv1.NamedCluster{
+			{Name: "insecure", Cluster: apiv1.Cluster{Server: "https://insecure.example.com", InsecureSkipTLSVerify: true}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "no-plugin", AuthInfo: apiv1.AuthInfo{Exec: &apiv1.ExecConfig{Command: "gke-gcloud-auth-plugin"}}},
+		},
+	})
+
+	findings := diagnoseKubeconfigs([]string{first, second, missing}, now)
+
+	type key struct {
+		severity DoctorSeverity
+		source   string
+		typ      ChangeItemType
+		name     string
+	}
+
+	want := map[key]bool{
+		{SeverityError, first, ChangeCluster, "bad-ca"}:      true,
+		{SeverityError, first, ChangeContext, "dangling"}:    true,
+		{SeverityWarning, first, ChangeContext, "good"}:      true,
+		{SeverityError, first, ChangeUser, "expired"}:        true,
+		{SeverityWarning, second, ChangeCluster, "insecure"}: true,
+		{SeverityError, second, ChangeUser, "no-plugin"}:     true,
+		{SeverityError, missing, "", ""}:                     true,
+	}
+
+	got := make(map[key]bool)
+	for _, f := range findings {
+		got[key{f.Severity, f.Source, f.Type, f.Name}] = true
+	}
+
+	for k := range want {
+		if !got[k] {
+			t.Errorf("diagnoseKubeconfigs() missing finding %+v", k)
+		}
+	}
+
+	for k := range got {
+		if !want[k] {
+			t.Errorf("diagnoseKubeconfigs() unexpected finding %+v", k)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/urfave/cli/v2"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// editHeader is prepended to the document opened in the editor. Comments are dropped when parsing.
+const editHeader = `# Edit the context below together with its cluster and user, then save and close the editor.
+# Renaming a cluster or user also updates the other contexts referring to it. Removing an entry
+# deletes it from the original kubeconfig. Leave the document unchanged to cancel.
+`
+
+func renameAction(c *cli.Context) error {
+	if c.Args().Len() != 2 {
+		return fmt.Errorf("usage: ksw rename <old> <new>")
+	}
+
+	oldName, newName := c.Args().Get(0), c.Args().Get(1)
+	kubeconfigPath := getOriginalKubeconfigPath()
+
+	config, err := readKubeconfig(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+
+	contexts := contextsMap(config.Contexts)
+
+	if _, ok := contexts[oldName]; !ok {
+		return fmt.Errorf("context %q not found", oldName)
+	}
+
+	if _, ok := contexts[newName]; ok {
+		return fmt.Errorf("context %q already exists", newName)
+	}
+
+	diff := KubeconfigDiff{ContextsRenamed: []KubeconfigRename{{From: oldName, To: newName}}}
+
+	applied, err := applyChangesToOriginal(kubeconfigPath, diff)
+	if err != nil || !applied {
+		return err
+	}
+
+	fmt.Printf("Context %q renamed to %q.\n", oldName, newName)
+
+	return nil
+}
+
+// editDocument returns the YAML document used to edit contextName: the context with its cluster and user.
+func editDocument(config apiv1.Config, contextName string) ([]byte, error) {
+	mini, err := minifyConfig(config, contextName)
+	if err != nil {
+		return nil, fmt.Errorf("context %q not found", contextName)
+	}
+
+	// The current context of the original is left alone, so it is not part of the document
+	mini.CurrentContext = ""
+
+	b, err := yaml.Marshal(mini)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(editHeader), b...), nil
+}
+
+// parseEditedDocument validates an edited document and returns the changes it makes to the
+// context, cluster and user it was created from.
+func parseEditedDocument(config apiv1.Config, contextName string, edited []byte) (KubeconfigDiff, error) {
+	var doc apiv1.Config
+	if err := yaml.Unmarshal(edited, &doc); err != nil {
+		return KubeconfigDiff{}, fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	if len(doc.Contexts) != 1 {
+		return KubeconfigDiff{}, fmt.Errorf("the document must contain exactly one context, found %d", len(doc.Contexts))
+	}
+
+	for _, x := range doc.Contexts {
+		if x.Name == "" {
+			return KubeconfigDiff{}, errors.New("context name must not be empty")
+		}
+	}
+
+	for _, x := range doc.Clusters {
+		if x.Name == "" {
+			return KubeconfigDiff{}, errors.New("cluster name must not be empty")
+		}
+
+		if x.Cluster.Server == "" {
+			return KubeconfigDiff{}, fmt.Errorf("cluster %q has no server", x.Name)
+		}
+	}
+
+	for _, x := range doc.AuthInfos {
+		if x.Name == "" {
+			return KubeconfigDiff{}, errors.New("user name must not be empty")
+		}
+	}
+
+	mini, err := minifyConfig(config, contextName)
+	if err != nil {
+		return KubeconfigDiff{}, fmt.Errorf("context %q not found", contextName)
+	}
+
+	mini.CurrentContext = ""
+
+	diff := computeKubeconfigDiff(*mini, doc, false)
+
+	// Entries that were added or renamed in the document must not overwrite other entries of the original
+	for _, x := range diff.ContextsAdded {
+		if _, exists := contextsMap(config.Contexts)[x.Name]; exists {
+			return KubeconfigDiff{}, fmt.Errorf("context %q already exists", x.Name)
+		}
+	}
+
+	for _, x := range diff.ClustersAdded {
+		if _, exists := clustersMap(config.Clusters)[x.Name]; exists {
+			return KubeconfigDiff{}, fmt.Errorf("cluster %q already exists", x.Name)
+		}
+	}
+
+	for _, x := range diff.UsersAdded {
+		if _, exists := usersMap(config.AuthInfos)[x.Name]; exists {
+			return KubeconfigDiff{}, fmt.Errorf("user %q already exists", x.Name)
+		}
+	}
+
+	for _, r := range diff.ContextsRenamed {
+		if _, exists := contextsMap(config.Contexts)[r.To]; exists {
+			return KubeconfigDiff{}, fmt.Errorf("context %q already exists", r.To)
+		}
+	}
+
+	for _, r := range diff.ClustersRenamed {
+		if _, exists := clustersMap(config.Clusters)[r.To]; exists {
+			return KubeconfigDiff{}, fmt.Errorf("cluster %q already exists", r.To)
+		}
+	}
+
+	for _, r := range diff.UsersRenamed {
+		if _, exists := usersMap(config.AuthInfos)[r.To]; exists {
+			return KubeconfigDiff{}, fmt.Errorf("user %q already exists", r.To)
+		}
+	}
+
+	if dangling := newDanglingReferences(config, applyDiff(config, diff)); len(dangling) > 0 {
+		return KubeconfigDiff{}, fmt.Errorf("%s", dangling[0])
+	}
+
+	return diff, nil
+}
+
+// runEditor opens path in $VISUAL or $EDITOR, falling back to vi.
+func runEditor(path string) error {
+	editor := cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi")
+
+	cmd := exec.Command("/bin/sh", "-c", editor+" "+shellQuote(path))
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+
+	return nil
+}
+
+func editAction(c *cli.Context) error {
+	contextName := c.Args().First()
+	if contextName == "" {
+		return fmt.Errorf("context name required")
+	}
+
+	kubeconfigPath := getOriginalKubeconfigPath()
+
+	config, err := readKubeconfig(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+
+	doc, err := editDocument(config, contextName)
+	if err != nil {
+		return err
+	}
+
+	// The document holds credentials, so it is kept in the private session directory
+	dir, err := sessionDir(loadConfig())
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, fmt.Sprintf("edit-%s.*.yaml", filepath.Base(contextName)))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+
+	_, err = f.Write(doc)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		if err := runEditor(f.Name()); err != nil {
+			return err
+		}
+
+		edited, err := os.ReadFile(f.Name())
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(edited, doc) || len(strings.TrimSpace(string(edited))) == 0 {
+			fmt.Println("Edit cancelled, no changes made.")
+
+			return nil
+		}
+
+		diff, err := parseEditedDocument(config, contextName, edited)
+		if err == nil {
+			if !diff.HasChanges() {
+				fmt.Println("No changes made.")
+
+				return nil
+			}
+
+			applied, err := applyChangesToOriginal(kubeconfigPath, diff)
+			if err != nil || !applied {
+				return err
+			}
+
+			fmt.Printf("Context %q updated.\n", contextName)
+
+			return nil
+		}
+
+		fmt.Printf("\033[31mError:\033[0m %v\n", err)
+
+		again, confirmErr := confirm("Edit again?")
+		if confirmErr != nil {
+			return fmt.Errorf("error confirming changes: %w", confirmErr)
+		}
+
+		if !again {
+			fmt.Println("Edit cancelled, no changes made.")
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+func TestParseEditedDocument(t *testing.T) {
+	config := apiv1.Config{
+		CurrentContext: "dev",
+		Contexts: []apiv1.NamedContext{
+			{Name: "dev", Context: apiv1.Context{Cluster: "shared", AuthInfo: "dev-user"}},
+			{Name: "prod", Context: apiv1.Context{Cluster: "shared", AuthInfo: "prod-user"}},
+		},
+		Clusters: []apiv1.NamedCluster{
+			{Name: "shared", Cluster: apiv1.Cluster{Server: "https://k8s.example.com"}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "dev-user", AuthInfo: apiv1.AuthInfo{Token: "dev"}},
+			{Name: "prod-user", AuthInfo: apiv1.AuthInfo{Token: "prod"}},
+		},
+	}
+
+	doc, err := editDocument(config, "dev")
+	if err != nil {
+		t.Fatalf("editDocument() error = %v", err)
+	}
+
+	if !strings.HasPrefix(string(doc), "#") || strings.Contains(string(doc), "current-context: dev") {
+		t.Errorf("editDocument() = %s", doc)
+	}
+
+	edit := func(t *testing.T, change func(*apiv1.Config)) []byte {
+		t.Helper()
+
+		var c apiv1.Config
+		if err := yaml.Unmarshal(doc, &c); err != nil {
+			t.Fatalf("Failed to parse document: %v", err)
+		}
+
+		change(&c)
+
+		b, err := yaml.Marshal(c)
+		if err != nil {
+			t.Fatalf("Failed to marshal document: %v", err)
+		}
+
+		return b
+	}
+
+	tests := []struct {
+		name    string
+		change  func(*apiv1.Config)
+		want    KubeconfigDiff
+		wantErr string
+	}{
+		{
+			name:   "unchanged",
+			change: func(c *apiv1.Config) {},
+			want:   KubeconfigDiff{},
+		},
+		{
+			name:   "namespace changed",
+			change: func(c *apiv1.Config) { c.Contexts[0].Context.Namespace = "apps" },
+			want: KubeconfigDiff{ContextsModified: []apiv1.NamedContext{
+				{Name: "dev", Context: apiv1.Context{Cluster: "shared", AuthInfo: "dev-user", Namespace: "apps"}},
+			}},
+		},
+		{
+			name: "context and user renamed",
+			change: func(c *apiv1.Config) {
+				c.Contexts[0].Name = "development"
+				c.Contexts[0].Context.AuthInfo = "developer"
+				c.AuthInfos[0].Name = "developer"
+			},
+			want: KubeconfigDiff{
+				ContextsRenamed: []KubeconfigRename{{From: "dev", To: "development"}},
+				UsersRenamed:    []KubeconfigRename{{From: "dev-user", To: "developer"}},
+			},
+		},
+		{
+			name:    "renamed to existing context",
+			change:  func(c *apiv1.Config) { c.Contexts[0].Name = "prod" },
+			wantErr: `context "prod" already exists`,
+		},
+		{
+			name:    "renamed to existing user",
+			change:  func(c *apiv1.Config) { c.AuthInfos[0].Name = "prod-user"; c.Contexts[0].Context.AuthInfo = "prod-user" },
+			wantErr: `user "prod-user" already exists`,
+		},
+		{
+			name:    "shared cluster removed",
+			change:  func(c *apiv1.Config) { c.Clusters = nil },
+			wantErr: `references missing Cluster "shared"`,
+		},
+		{
+			name:    "second context",
+			change:  func(c *apiv1.Config) { c.Contexts = append(c.Contexts, c.Contexts[0]) },
+			wantErr: "exactly one context",
+		},
+		{
+			name:    "cluster without server",
+			change:  func(c *apiv1.Config) { c.Clusters[0].Cluster.Server = "" },
+			wantErr: "has no server",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEditedDocument(config, "dev", edit(t, tt.change))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseEditedDocument() error = %v, want containing %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseEditedDocument() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEditedDocument() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid yaml", func(t *testing.T) {
+		if _, err := parseEditedDocument(config, "dev", []byte("contexts: [")); err == nil {
+			t.Errorf("parseEditedDocument() expected error for invalid YAML")
+		}
+	})
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ghodss/yaml"
+)
+
+// kubeconfigEncryption identifies how a kubeconfig source is encrypted at rest.
+type kubeconfigEncryption string
+
+const (
+	encryptionNone     kubeconfigEncryption = ""
+	encryptionAge      kubeconfigEncryption = "age"
+	encryptionAgeArmor kubeconfigEncryption = "age-armor"
+	encryptionSops     kubeconfigEncryption = "sops"
+)
+
+const ageHeader = "age-encryption.org/v1\n"
+
+var sopsCommand = "sops"
+
+// detectEncryption inspects raw kubeconfig bytes and reports how they are encrypted.
+func detectEncryption(data []byte) kubeconfigEncryption {
+	if bytes.HasPrefix(data, []byte(ageHeader)) {
+		return encryptionAge
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header)) {
+		return encryptionAgeArmor
+	}
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err == nil {
+		if _, ok := doc["sops"]; ok {
+			return encryptionSops
+		}
+	}
+
+	return encryptionNone
+}
+
+// ageIdentityFile returns the age key file used for encrypted kubeconfig sources.
+// It defaults to the same location sops uses so both tools can share one key.
+func ageIdentityFile(cfg KswConfig) string {
+	if cfg.Kubeconfig.Encryption.AgeIdentityFile != "" {
+		return cfg.Kubeconfig.Encryption.AgeIdentityFile
+	}
+
+	if path := os.Getenv("SOPS_AGE_KEY_FILE"); path != "" {
+		return path
+	}
+
+	home, err := userHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".config", "sops", "age", "keys.txt")
+}
+
+func loadAgeIdentities(path string) ([]age.Identity, error) {
+	if path == "" {
+		return nil, fmt.Errorf("no age identity file configured")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open age identity file: %w", err)
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age identity file %s: %w", path, err)
+	}
+
+	return identities, nil
+}
+
+// readKubeconfigBytes reads a kubeconfig file and decrypts it in memory when it is
+// age or sops encrypted. The plaintext is never written to disk.
+func readKubeconfigBytes(path string) ([]byte, kubeconfigEncryption, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, encryptionNone, err
+	}
+
+	enc := detectEncryption(data)
+
+	switch enc {
+	case encryptionAge, encryptionAgeArmor:
+		identities, err := loadAgeIdentities(ageIdentityFile(loadConfig()))
+		if err != nil {
+			return nil, enc, err
+		}
+
+		var src io.Reader = bytes.NewReader(data)
+		if enc == encryptionAgeArmor {
+			src = armor.NewReader(src)
+		}
+
+		r, err := age.Decrypt(src, identities...)
+		if err != nil {
+			return nil, enc, fmt.Errorf("failed to decrypt %s: %w", path, err)
+		}
+
+		plain, err := io.ReadAll(r)
+		if err != nil {
+			return nil, enc, fmt.Errorf("failed to decrypt %s: %w", path, err)
+		}
+
+		return plain, enc, nil
+	case encryptionSops:
+		cmd := exec.Command(sopsCommand, "--decrypt", "--input-type", "yaml", "--output-type", "yaml", path)
+		cmd.Stderr = os.Stderr
+
+		plain, err := cmd.Output()
+		if err != nil {
+			return nil, enc, fmt.Errorf("failed to decrypt %s with sops: %w", path, err)
+		}
+
+		return plain, enc, nil
+	default:
+		return data, enc, nil
+	}
+}
+
+// ageRecipientsName is the file listing the recipients age encrypted kubeconfigs in the same
+// directory are re-encrypted to, in the format of age -R.
+const ageRecipientsName = ".age-recipients"
+
+// ageRecipients returns the recipients to re-encrypt the age encrypted kubeconfig at path to. They are
+// read from the .age-recipients file next to it when there is one. Otherwise a file encrypted to a
+// single recipient is re-encrypted to the local identity that decrypts it, and a file encrypted to
+// several recipients is refused since they cannot be recovered from it. New files are encrypted to
+// the local identities.
+func ageRecipients(path string, identities []age.Identity) ([]age.Recipient, error) {
+	recipientsFile := filepath.Join(filepath.Dir(path), ageRecipientsName)
+
+	if f, err := os.Open(recipientsFile); err == nil {
+		defer func() { _ = f.Close() }()
+
+		recipients, err := age.ParseRecipients(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", recipientsFile, err)
+		}
+
+		return recipients, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		var recipients []age.Recipient
+
+		for _, identity := range identities {
+			if x, ok := identity.(*age.X25519Identity); ok {
+				recipients = append(recipients, x.Recipient())
+			}
+		}
+
+		if len(recipients) == 0 {
+			return nil, fmt.Errorf("no X25519 identities available to encrypt %s", path)
+		}
+
+		return recipients, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if n := ageStanzaCount(data); n != 1 {
+		return nil, fmt.Errorf("%s is encrypted to %d recipients, list them in %s to write it back", path, n, recipientsFile)
+	}
+
+	for _, identity := range identities {
+		x, ok := identity.(*age.X25519Identity)
+		if !ok {
+			continue
+		}
+
+		var src io.Reader = bytes.NewReader(data)
+		if detectEncryption(data) == encryptionAgeArmor {
+			src = armor.NewReader(src)
+		}
+
+		if _, err := age.Decrypt(src, x); err == nil {
+			return []age.Recipient{x.Recipient()}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no X25519 identity decrypts %s, list its recipients in %s to write it back", path, recipientsFile)
+}
+
+// ageStanzaCount returns the number of recipient stanzas in the header of age encrypted data.
+func ageStanzaCount(data []byte) int {
+	if detectEncryption(data) == encryptionAgeArmor {
+		decoded, err := io.ReadAll(armor.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			return 0
+		}
+
+		data = decoded
+	}
+
+	n := 0
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("---")) {
+			break
+		}
+
+		if bytes.HasPrefix(line, []byte("-> ")) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// writeKubeconfigBytes writes plaintext kubeconfig bytes to path, encrypting them
+// the same way the file was encrypted when it was read.
+func writeKubeconfigBytes(path string, plain []byte, enc kubeconfigEncryption) error {
+	switch enc {
+	case encryptionAge, encryptionAgeArmor:
+		identities, err := loadAgeIdentities(ageIdentityFile(loadConfig()))
+		if err != nil {
+			return err
+		}
+
+		recipients, err := ageRecipients(path, identities)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+
+		var dst io.WriteCloser = nopWriteCloser{&buf}
+		if enc == encryptionAgeArmor {
+			dst = armor.NewWriter(&buf)
+		}
+
+		w, err := age.Encrypt(dst, recipients...)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+
+		if err := w.Close(); err != nil {
+			return err
+		}
+
+		if err := dst.Close(); err != nil {
+			return err
+		}
+
+		return os.WriteFile(path, buf.Bytes(), 0600)
+	case encryptionSops:
+		// sops picks the keys from the creation rules in .sops.yaml that match the original path
+		cmd := exec.Command(sopsCommand, "--encrypt", "--input-type", "yaml", "--output-type", "yaml",
+			"--filename-override", path, "/dev/stdin")
+		cmd.Stdin = bytes.NewReader(plain)
+		cmd.Stderr = os.Stderr
+
+		encrypted, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s with sops: %w", path, err)
+		}
+
+		return os.WriteFile(path, encrypted, 0600)
+	default:
+		return os.WriteFile(path, plain, 0600)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/ghodss/yaml"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+const encryptionTestKubeconfig = `apiVersion: v1
+kind: Config
+contexts:
+- name: prod-cluster
+  context:
+    cluster: prod
+    user: prod-user
+- name: dev-cluster
+  context:
+    cluster: dev
+    user: dev-user
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+- name: dev
+  cluster:
+    server: https://dev.example.com
+users:
+- name: prod-user
+  user:
+    token: prod-token
+- name: dev-user
+  user:
+    token: dev-token
+`
+
+// setupAgeIdentity creates an age identity file and points the ksw config at it.
+func setupAgeIdentity(t *testing.T) *age.X25519Identity {
+	t.Helper()
+
+	origUserHomeDir := userHomeDir
+
+	t.Cleanup(func() {
+		userHomeDir = origUserHomeDir
+	})
+
+	home := t.TempDir()
+	userHomeDir = func() (string, error) {
+		return home, nil
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("Failed to generate age identity: %v", err)
+	}
+
+	keyPath := filepath.Join(home, "age.key")
+	if err := os.WriteFile(keyPath, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write age identity: %v", err)
+	}
+
+	content := []byte("kubeconfig:\n  encryption:\n    age_identity_file: " + keyPath + "\n")
+	if err := os.WriteFile(filepath.Join(home, ".ksw.yaml"), content, 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	return identity
+}
+
+func TestDetectEncryption(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want kubeconfigEncryption
+	}{
+		{name: "plaintext", data: encryptionTestKubeconfig, want: encryptionNone},
+		{name: "age binary", data: ageHeader + "-> X25519 abc\n", want: encryptionAge},
+		{name: "age armor", data: "-----BEGIN AGE ENCRYPTED FILE-----\nabc\n", want: encryptionAgeArmor},
+		{name: "sops", data: "apiVersion: ENC[AES256_GCM,data:abc]\nsops:\n  mac: ENC[abc]\n", want: encryptionSops},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectEncryption([]byte(tt.data)); got != tt.want {
+				t.Errorf("detectEncryption() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgeEncryptedKubeconfig(t *testing.T) {
+	setupAgeIdentity(t)
+
+	for _, enc := range []kubeconfigEncryption{encryptionAge, encryptionAgeArmor} {
+		t.Run(string(enc), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.age")
+
+			if err := writeKubeconfigBytes(path, []byte(encryptionTestKubeconfig), enc); err != nil {
+				t.Fatalf("writeKubeconfigBytes() error = %v", err)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read encrypted kubeconfig: %v", err)
+			}
+
+			if bytes.Contains(raw, []byte("prod-token")) {
+				t.Fatal("writeKubeconfigBytes() wrote plaintext secrets")
+			}
+
+			if got := detectEncryption(raw); got != enc {
+				t.Errorf("detectEncryption() = %q, want %q", got, enc)
+			}
+
+			contexts, err := listContexts(path)
+			if err != nil {
+				t.Fatalf("listContexts() error = %v", err)
+			}
+
+			if len(contexts) != 2 {
+				t.Errorf("listContexts() = %v, want 2 contexts", contexts)
+			}
+
+			// Encrypted sources are always minified into the session kubeconfig
+			gotBytes, err := generateKubeconfig(path, "prod-cluster")
+			if err != nil {
+				t.Fatalf("generateKubeconfig() error = %v", err)
+			}
+
+			var gotConfig apiv1.Config
+			if err := yaml.Unmarshal(gotBytes, &gotConfig); err != nil {
+				t.Fatalf("Failed to unmarshal generated kubeconfig: %v", err)
+			}
+
+			if len(gotConfig.Contexts) != 1 || strings.Contains(string(gotBytes), "dev-token") {
+				t.Errorf("generateKubeconfig() leaked other contexts:\n%s", gotBytes)
+			}
+		})
+	}
+}
+
+func TestAgeEncryptedKubeconfigWrongIdentity(t *testing.T) {
+	setupAgeIdentity(t)
+
+	path := filepath.Join(t.TempDir(), "config.age")
+	if err := writeKubeconfigBytes(path, []byte(encryptionTestKubeconfig), encryptionAge); err != nil {
+		t.Fatalf("writeKubeconfigBytes() error = %v", err)
+	}
+
+	// Replace the identity with an unrelated one
+	setupAgeIdentity(t)
+
+	if _, err := listContexts(path); err == nil {
+		t.Error("listContexts() expected error when decrypting with the wrong identity")
+	}
+}
+
+// encryptAge encrypts encryptionTestKubeconfig to recipients.
+func encryptAge(t *testing.T, recipients ...age.Recipient) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w, err := age.Encrypt(&buf, recipients...)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	if _, err := w.Write([]byte(encryptionTestKubeconfig)); err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestAgeKeepsRecipients(t *testing.T) {
+	identity := setupAgeIdentity(t)
+
+	teammate, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("Failed to generate age identity: %v", err)
+	}
+
+	decrypts := func(t *testing.T, path string, identity age.Identity) bool {
+		t.Helper()
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read encrypted kubeconfig: %v", err)
+		}
+
+		_, err = age.Decrypt(bytes.NewReader(raw), identity)
+
+		return err == nil
+	}
+
+	t.Run("several recipients without a recipients file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.age")
+		original := encryptAge(t, identity.Recipient(), teammate.Recipient())
+		writeTestFile(t, path, string(original))
+
+		if err := writeKubeconfigBytes(path, []byte(encryptionTestKubeconfig), encryptionAge); err == nil {
+			t.Fatal("writeKubeconfigBytes() expected error when the recipients cannot be reproduced")
+		}
+
+		if raw, _ := os.ReadFile(path); !bytes.Equal(raw, original) {
+			t.Error("writeKubeconfigBytes() changed the file it refused to write")
+		}
+	})
+
+	t.Run("recipients file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.age")
+		writeTestFile(t, path, string(encryptAge(t, identity.Recipient(), teammate.Recipient())))
+		writeTestFile(t, filepath.Join(dir, ageRecipientsName),
+			"# team\n"+identity.Recipient().String()+"\n"+teammate.Recipient().String()+"\n")
+
+		if err := writeKubeconfigBytes(path, []byte(encryptionTestKubeconfig), encryptionAge); err != nil {
+			t.Fatalf("writeKubeconfigBytes() error = %v", err)
+		}
+
+		if !decrypts(t, path, identity) || !decrypts(t, path, teammate) {
+			t.Error("writeKubeconfigBytes() did not re-encrypt to every listed recipient")
+		}
+	})
+
+	t.Run("single recipient", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.age")
+		writeTestFile(t, path, string(encryptAge(t, identity.Recipient())))
+
+		if err := writeKubeconfigBytes(path, []byte(encryptionTestKubeconfig), encryptionAge); err != nil {
+			t.Fatalf("writeKubeconfigBytes() error = %v", err)
+		}
+
+		if !decrypts(t, path, identity) {
+			t.Error("writeKubeconfigBytes() did not re-encrypt to the original recipient")
+		}
+	})
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// execCacheTTL is how long a successful exec plugin run is remembered when the
+// plugin does not report an expiration timestamp.
+const execCacheTTL = 15 * time.Minute
+
+// execCredential is the subset of the client.authentication.k8s.io ExecCredential object ksw reads.
+type execCredential struct {
+	Status *execCredentialStatus `json:"status"`
+}
+
+// execCredentialStatus holds the credential returned by an exec plugin.
+type execCredentialStatus struct {
+	ExpirationTimestamp   *time.Time `json:"expirationTimestamp"`
+	Token                 string     `json:"token"`
+	ClientCertificateData string     `json:"clientCertificateData"`
+	ClientKeyData         string     `json:"clientKeyData"`
+}
+
+// execCacheEntry records when a prefetched credential expires. Credentials themselves are never cached.
+type execCacheEntry struct {
+	Expires time.Time `json:"expires"`
+}
+
+// execCachePath returns the cache file for an exec plugin configuration.
+func execCachePath(dir, userName string, plugin *apiv1.ExecConfig) string {
+	b, _ := json.Marshal(struct {
+		User   string            `json:"user"`
+		Plugin *apiv1.ExecConfig `json:"plugin"`
+	}{userName, plugin})
+
+	sum := sha256.Sum256(b)
+
+	return filepath.Join(dir, "exec-cache", hex.EncodeToString(sum[:])+".json")
+}
+
+func readExecCache(path string, now time.Time) bool {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	var entry execCacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return false
+	}
+
+	return now.Before(entry.Expires)
+}
+
+func writeExecCache(path string, expires time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(execCacheEntry{Expires: expires})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0600)
+}
+
+// runExecPlugin runs an exec credential plugin the same way client-go does and
+// returns when the obtained credential expires.
+func runExecPlugin(ctx context.Context, source string, plugin *apiv1.ExecConfig, stderr io.Writer, now time.Time) (time.Time, error) {
+	status, err := execPluginCredential(ctx, source, plugin, stderr)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if status.ExpirationTimestamp != nil {
+		return *status.ExpirationTimestamp, nil
+	}
+
+	return now.Add(execCacheTTL), nil
+}
+
+// execPluginCredential runs an exec credential plugin the same way client-go does and returns the credential.
+func execPluginCredential(ctx context.Context, source string, plugin *apiv1.ExecConfig, stderr io.Writer) (execCredentialStatus, error) {
+	command := plugin.Command
+	if strings.ContainsRune(command, filepath.Separator) {
+		command = resolveKubeconfigPath(source, command)
+	}
+
+	cmd := exec.CommandContext(ctx, command, plugin.Args...)
+	cmd.Stderr = stderr
+	cmd.Env = os.Environ()
+
+	for _, env := range plugin.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+
+	interactive := plugin.InteractiveMode != apiv1.NeverExecInteractiveMode && term.IsTerminal(int(os.Stdin.Fd()))
+	if interactive {
+		cmd.Stdin = os.Stdin
+	}
+
+	execInfo, err := json.Marshal(map[string]any{
+		"apiVersion": plugin.APIVersion,
+		"kind":       "ExecCredential",
+		"spec":       map[string]any{"interactive": interactive},
+	})
+	if err != nil {
+		return execCredentialStatus{}, err
+	}
+
+	cmd.Env = append(cmd.Env, "KUBERNETES_EXEC_INFO="+string(execInfo))
+
+	out, err := cmd.Output()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return execCredentialStatus{}, fmt.Errorf("exec plugin %s timed out", plugin.Command)
+	}
+
+	if err != nil {
+		return execCredentialStatus{}, fmt.Errorf("exec plugin %s failed: %w", plugin.Command, err)
+	}
+
+	var cred execCredential
+	if err := json.Unmarshal(out, &cred); err != nil {
+		return execCredentialStatus{}, fmt.Errorf("exec plugin %s returned invalid output: %w", plugin.Command, err)
+	}
+
+	if cred.Status == nil || (cred.Status.Token == "" && cred.Status.ClientCertificateData == "") {
+		return execCredentialStatus{}, fmt.Errorf("exec plugin %s returned no credentials", plugin.Command)
+	}
+
+	return *cred.Status, nil
+}
+
+// startSpinner shows a spinner with message on stderr until the returned function is called.
+// When stderr is not a terminal the message is logged once instead.
+func startSpinner(message string) (stop func()) {
+	if !term.IsTerminal(int(os.Stderr.Fd())) {
+		logf("%s", message)
+
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+		ticker := time.NewTicker(100 * time.Millisecond)
+
+		defer ticker.Stop()
+
+		for i := 0; ; i++ {
+			fmt.Fprintf(os.Stderr, "\r\033[K%s: %s %s", logPrefix, frames[i%len(frames)], message)
+
+			select {
+			case <-done:
+				fmt.Fprint(os.Stderr, "\r\033[K")
+
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// stopOnWrite stops a spinner before the first write, so prompts printed by
+// interactive exec plugins are not overwritten.
+type stopOnWrite struct {
+	w    io.Writer
+	stop func()
+}
+
+func (s stopOnWrite) Write(p []byte) (int, error) {
+	s.stop()
+
+	return s.w.Write(p)
+}
+
+// prefetchExecCredentials runs the exec plugin of the user referenced by contextName in config, the
+// parsed kubeconfig at kubeconfigPath, ahead of time so interactive logins happen before the session
+// starts rather than on the first kubectl call.
+// Failures are reported as warnings. Successful runs are cached in the session directory until the
+// credential expires, which makes switching back to the context instant.
+func prefetchExecCredentials(kubeconfigPath string, config apiv1.Config, contextName string, cfg KswConfig) {
+	if !cfg.Credentials.PrefetchExec {
+		return
+	}
+
+	ctx, ok := contextsMap(config.Contexts)[contextName]
+	if !ok {
+		return
+	}
+
+	user, ok := usersMap(config.AuthInfos)[ctx.AuthInfo]
+	if !ok || user.Exec == nil {
+		return
+	}
+
+	now := time.Now()
+
+	var cachePath string
+	if dir, err := sessionDir(cfg); err == nil {
+		cachePath = execCachePath(dir, ctx.AuthInfo, user.Exec)
+		if readExecCache(cachePath, now) {
+			return
+		}
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Credentials.PrefetchTimeout))
+	defer cancel()
+
+	stop := startSpinner(fmt.Sprintf("refreshing credentials for context %s", contextName))
+	expires, runErr := runExecPlugin(timeoutCtx, kubeconfigPath, user.Exec, stopOnWrite{w: os.Stderr, stop: stop}, now)
+
+	stop()
+
+	if runErr != nil {
+		logf("warning: authentication for context %s failed: %v", contextName, runErr)
+
+		return
+	}
+
+	if cachePath != "" {
+		if err := writeExecCache(cachePath, expires); err != nil {
+			logf("warning: failed to cache exec credential state: %v", err)
+		}
+	}
+}
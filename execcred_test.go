@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// writeTestPlugin writes an executable shell script acting as an exec credential plugin.
+func writeTestPlugin(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "plugin")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0700); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+
+	return path
+}
+
+func TestRunExecPlugin(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		script  string
+		timeout time.Duration
+		want    time.Time
+		wantErr string
+	}{
+		{
+			name: "token with expiration",
+			script: `case "$KUBERNETES_EXEC_INFO" in *ExecCredential*) ;; *) exit 3 ;; esac
+echo '{"kind":"ExecCredential","status":{"token":"abc","expirationTimestamp":"2026-01-01T01:00:00Z"}}'`,
+			want: now.Add(time.Hour),
+		},
+		{
+			name:   "token without expiration",
+			script: `echo '{"kind":"ExecCredential","status":{"token":"abc"}}'`,
+			want:   now.Add(execCacheTTL),
+		},
+		{
+			name:    "plugin failure",
+			script:  "echo 'login required' >&2; exit 1",
+			wantErr: "failed",
+		},
+		{
+			name:    "no credentials",
+			script:  `echo '{"kind":"ExecCredential","status":{}}'`,
+			wantErr: "no credentials",
+		},
+		{
+			name:    "timeout",
+			script:  "exec sleep 5",
+			timeout: 100 * time.Millisecond,
+			wantErr: "timed out",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeout := tt.timeout
+			if timeout == 0 {
+				timeout = 10 * time.Second
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			plugin := &apiv1.ExecConfig{
+				Command:         writeTestPlugin(t, tt.script),
+				APIVersion:      "client.authentication.k8s.io/v1",
+				InteractiveMode: apiv1.NeverExecInteractiveMode,
+			}
+
+			got, err := runExecPlugin(ctx, "/nonexistent/config", plugin, io.Discard, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("runExecPlugin() error = %v, want containing %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("runExecPlugin() error = %v", err)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Errorf("runExecPlugin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecCache(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	plugin := &apiv1.ExecConfig{Command: "aws", Args: []string{"eks", "get-token"}}
+
+	path := execCachePath(dir, "prod-user", plugin)
+	if other := execCachePath(dir, "dev-user", plugin); other == path {
+		t.Errorf("execCachePath() is the same for different users: %v", path)
+	}
+
+	if readExecCache(path, now) {
+		t.Error("readExecCache() = true before anything was cached")
+	}
+
+	if err := writeExecCache(path, now.Add(time.Hour)); err != nil {
+		t.Fatalf("writeExecCache() error = %v", err)
+	}
+
+	if !readExecCache(path, now) {
+		t.Error("readExecCache() = false for a valid entry")
+	}
+
+	if readExecCache(path, now.Add(2*time.Hour)) {
+		t.Error("readExecCache() = true for an expired entry")
+	}
+}
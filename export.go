@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// Exec plugin handling modes for exported kubeconfigs.
+const (
+	exportExecKeep     = "keep"
+	exportExecStripEnv = "strip-env"
+	exportExecToken    = "token"
+)
+
+// exportConfig returns a standalone config with the given contexts and the clusters and users they reference.
+// The first context becomes the current context.
+func exportConfig(c apiv1.Config, contextNames []string) (apiv1.Config, error) {
+	var exported apiv1.Config
+
+	for i, name := range contextNames {
+		mini, err := minifyConfig(c, name)
+		if err != nil {
+			return apiv1.Config{}, fmt.Errorf("context %q not found", name)
+		}
+
+		if i == 0 {
+			exported = *mini
+
+			continue
+		}
+
+		for _, x := range mini.Contexts {
+			if !slices.ContainsFunc(exported.Contexts, func(y apiv1.NamedContext) bool { return y.Name == x.Name }) {
+				exported.Contexts = append(exported.Contexts, x)
+			}
+		}
+
+		for _, x := range mini.Clusters {
+			if !slices.ContainsFunc(exported.Clusters, func(y apiv1.NamedCluster) bool { return y.Name == x.Name }) {
+				exported.Clusters = append(exported.Clusters, x)
+			}
+		}
+
+		for _, x := range mini.AuthInfos {
+			if !slices.ContainsFunc(exported.AuthInfos, func(y apiv1.NamedAuthInfo) bool { return y.Name == x.Name }) {
+				exported.AuthInfos = append(exported.AuthInfos, x)
+			}
+		}
+	}
+
+	return exported, nil
+}
+
+// readReferencedFile reads a file referenced by a kubeconfig loaded from source.
+func readReferencedFile(source, path string) ([]byte, error) {
+	data, err := os.ReadFile(resolveKubeconfigPath(source, path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inline %s: %w", path, err)
+	}
+
+	return data, nil
+}
+
+// flattenConfig inlines the certificate and key files referenced by c as *-data fields,
+// so the config no longer depends on files next to source.
+func flattenConfig(c apiv1.Config, source string) (apiv1.Config, error) {
+	clusters := make([]apiv1.NamedCluster, len(c.Clusters))
+
+	for i, x := range c.Clusters {
+		if ca := x.Cluster.CertificateAuthority; ca != "" {
+			data, err := readReferencedFile(source, ca)
+			if err != nil {
+				return apiv1.Config{}, err
+			}
+
+			x.Cluster.CertificateAuthorityData = data
+			x.Cluster.CertificateAuthority = ""
+		}
+
+		clusters[i] = x
+	}
+
+	users := make([]apiv1.NamedAuthInfo, len(c.AuthInfos))
+
+	for i, x := range c.AuthInfos {
+		if cert := x.AuthInfo.ClientCertificate; cert != "" {
+			data, err := readReferencedFile(source, cert)
+			if err != nil {
+				return apiv1.Config{}, err
+			}
+
+			x.AuthInfo.ClientCertificateData = data
+			x.AuthInfo.ClientCertificate = ""
+		}
+
+		if key := x.AuthInfo.ClientKey; key != "" {
+			data, err := readReferencedFile(source, key)
+			if err != nil {
+				return apiv1.Config{}, err
+			}
+
+			x.AuthInfo.ClientKeyData = data
+			x.AuthInfo.ClientKey = ""
+		}
+
+		users[i] = x
+	}
+
+	c.Clusters = clusters
+	c.AuthInfos = users
+
+	return c, nil
+}
+
+// stripExecEnv removes the environment variables passed to exec plugins, which often hold
+// machine specific values such as profile names or local paths.
+func stripExecEnv(c apiv1.Config) apiv1.Config {
+	users := make([]apiv1.NamedAuthInfo, len(c.AuthInfos))
+
+	for i, x := range c.AuthInfos {
+		if x.AuthInfo.Exec != nil {
+			plugin := *x.AuthInfo.Exec
+			plugin.Env = nil
+			x.AuthInfo.Exec = &plugin
+		}
+
+		users[i] = x
+	}
+
+	c.AuthInfos = users
+
+	return c
+}
+
+// replaceExecWithCredentials runs the exec plugin of every user and replaces it with the
+// static token or client certificate it returned.
+func replaceExecWithCredentials(c apiv1.Config, source string, timeout time.Duration) (apiv1.Config, error) {
+	users := make([]apiv1.NamedAuthInfo, len(c.AuthInfos))
+
+	for i, x := range c.AuthInfos {
+		if x.AuthInfo.Exec != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+			stop := startSpinner(fmt.Sprintf("obtaining credentials for user %s", x.Name))
+			status, err := execPluginCredential(ctx, source, x.AuthInfo.Exec, stopOnWrite{w: os.Stderr, stop: stop})
+
+			stop()
+			cancel()
+
+			if err != nil {
+				return apiv1.Config{}, fmt.Errorf("user %s: %w", x.Name, err)
+			}
+
+			if status.ExpirationTimestamp != nil {
+				logf("credentials of user %s expire at %s", x.Name, formatExpiry(*status.ExpirationTimestamp, time.Now()))
+			}
+
+			x.AuthInfo.Exec = nil
+			x.AuthInfo.Token = status.Token
+
+			// client-go rejects users with both a certificate file and inline certificate data
+			if status.ClientCertificateData != "" {
+				x.AuthInfo.ClientCertificateData = []byte(status.ClientCertificateData)
+				x.AuthInfo.ClientKeyData = []byte(status.ClientKeyData)
+				x.AuthInfo.ClientCertificate = ""
+				x.AuthInfo.ClientKey = ""
+			}
+		}
+
+		users[i] = x
+	}
+
+	c.AuthInfos = users
+
+	return c, nil
+}
+
+// stdoutIsTerminal reports whether standard output is a terminal.
+var stdoutIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// exportBytes renders an exported kubeconfig. Kubeconfigs printed to a terminal are read rather than
+// used, so their secrets are redacted there unless showSecrets is set.
+func exportBytes(exported apiv1.Config, terminal, showSecrets bool) ([]byte, error) {
+	if !terminal {
+		return yaml.Marshal(exported)
+	}
+
+	rendered, err := renderConfig(exported, showSecrets)
+
+	return []byte(rendered), err
+}
+
+func exportAction(c *cli.Context) error {
+	execMode := c.String("exec")
+	if !slices.Contains([]string{exportExecKeep, exportExecStripEnv, exportExecToken}, execMode) {
+		return fmt.Errorf("unsupported exec mode %q: expected %s, %s or %s", execMode, exportExecKeep, exportExecStripEnv, exportExecToken)
+	}
+
+	source := getOriginalKubeconfigPath()
+
+	config, err := readKubeconfig(source)
+	if err != nil {
+		return err
+	}
+
+	names := c.Args().Slice()
+	if len(names) == 0 {
+		if names, err = findContexts("", nil, loadConfig().Finder.MultiSelect, c.Bool("show-secrets")); err != nil {
+			return err
+		}
+	}
+
+	exported, err := exportConfig(config, names)
+	if err != nil {
+		return err
+	}
+
+	exported = resolveImportedPaths(exported, source)
+
+	if c.Bool("flatten") {
+		if exported, err = flattenConfig(exported, source); err != nil {
+			return err
+		}
+	}
+
+	switch execMode {
+	case exportExecStripEnv:
+		exported = stripExecEnv(exported)
+	case exportExecToken:
+		if exported, err = replaceExecWithCredentials(exported, source, time.Duration(loadConfig().Credentials.PrefetchTimeout)); err != nil {
+			return err
+		}
+	}
+
+	output := c.String("output")
+	toStdout := output == "" || output == "-"
+	showSecrets := c.Bool("show-secrets")
+	terminal := toStdout && stdoutIsTerminal()
+
+	b, err := exportBytes(exported, terminal, showSecrets)
+	if err != nil {
+		return err
+	}
+
+	if toStdout {
+		if terminal && !showSecrets {
+			logf("secrets are redacted on a terminal, redirect the output or use --show-secrets to include them")
+		}
+
+		_, err = os.Stdout.Write(b)
+
+		return err
+	}
+
+	if err := os.WriteFile(output, b, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+
+	logf("exported %d context(s) to %s", len(exported.Contexts), output)
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+func TestExportConfig(t *testing.T) {
+	config := apiv1.Config{
+		CurrentContext: "c1",
+		Contexts: []apiv1.NamedContext{
+			{Name: "c1", Context: apiv1.Context{Cluster: "cluster1", AuthInfo: "user1"}},
+			{Name: "c2", Context: apiv1.Context{Cluster: "cluster1", AuthInfo: "user2"}},
+			{Name: "c3", Context: apiv1.Context{Cluster: "cluster3", AuthInfo: "user1"}},
+		},
+		Clusters: []apiv1.NamedCluster{
+			{Name: "cluster1", Cluster: apiv1.Cluster{Server: "https://c1.example.com"}},
+			{Name: "cluster3", Cluster: apiv1.Cluster{Server: "https://c3.example.com"}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "user1", AuthInfo: apiv1.AuthInfo{Token: "token1"}},
+			{Name: "user2", AuthInfo: apiv1.AuthInfo{Token: "token2"}},
+		},
+	}
+
+	t.Run("multiple contexts", func(t *testing.T) {
+		got, err := exportConfig(config, []string{"c2", "c3", "c2"})
+		if err != nil {
+			t.Fatalf("exportConfig() error = %v", err)
+		}
+
+		want := apiv1.Config{
+			CurrentContext: "c2",
+			Contexts: []apiv1.NamedContext{
+				{Name: "c2", Context: apiv1.Context{Cluster: "cluster1", AuthInfo: "user2"}},
+				{Name: "c3", Context: apiv1.Context{Cluster: "cluster3", AuthInfo: "user1"}},
+			},
+			Clusters: []apiv1.NamedCluster{
+				{Name: "cluster1", Cluster: apiv1.Cluster{Server: "https://c1.example.com"}},
+				{Name: "cluster3", Cluster: apiv1.Cluster{Server: "https://c3.example.com"}},
+			},
+			AuthInfos: []apiv1.NamedAuthInfo{
+				{Name: "user2", AuthInfo: apiv1.AuthInfo{Token: "token2"}},
+				{Name: "user1", AuthInfo: apiv1.AuthInfo{Token: "token1"}},
+			},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("exportConfig() =\n%+v\nwant\n%+v", got, want)
+		}
+	})
+
+	t.Run("unknown context", func(t *testing.T) {
+		if _, err := exportConfig(config, []string{"c1", "missing"}); err == nil {
+			t.Errorf("exportConfig() expected error for unknown context")
+		}
+	})
+}
+
+func TestFlattenConfig(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "config")
+
+	for name, content := range map[string]string{"ca.crt": "CA", "client.crt": "CERT", "client.key": "KEY"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	config := apiv1.Config{
+		Clusters: []apiv1.NamedCluster{
+			{Name: "c", Cluster: apiv1.Cluster{CertificateAuthority: "ca.crt"}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "u", AuthInfo: apiv1.AuthInfo{
+				ClientCertificate: filepath.Join(dir, "client.crt"),
+				ClientKey:         "client.key",
+			}},
+		},
+	}
+
+	got, err := flattenConfig(config, source)
+	if err != nil {
+		t.Fatalf("flattenConfig() error = %v", err)
+	}
+
+	wantCluster := apiv1.Cluster{CertificateAuthorityData: []byte("CA")}
+	if !reflect.DeepEqual(got.Clusters[0].Cluster, wantCluster) {
+		t.Errorf("cluster = %+v, want %+v", got.Clusters[0].Cluster, wantCluster)
+	}
+
+	wantUser := apiv1.AuthInfo{ClientCertificateData: []byte("CERT"), ClientKeyData: []byte("KEY")}
+	if !reflect.DeepEqual(got.AuthInfos[0].AuthInfo, wantUser) {
+		t.Errorf("user = %+v, want %+v", got.AuthInfos[0].AuthInfo, wantUser)
+	}
+
+	if config.Clusters[0].Cluster.CertificateAuthority != "ca.crt" {
+		t.Errorf("flattenConfig() modified its input")
+	}
+
+	config.Clusters[0].Cluster.CertificateAuthority = "missing.crt"
+	if _, err := flattenConfig(config, source); err == nil {
+		t.Errorf("flattenConfig() expected error for missing file")
+	}
+}
+
+func TestStripExecEnv(t *testing.T) {
+	plugin := &apiv1.ExecConfig{
+		Command: "aws",
+		Env:     []apiv1.ExecEnvVar{{Name: "AWS_PROFILE", Value: "work"}},
+	}
+
+	config := apiv1.Config{
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "exec", AuthInfo: apiv1.AuthInfo{Exec: plugin}},
+			{Name: "token", AuthInfo: apiv1.AuthInfo{Token: "abc"}},
+		},
+	}
+
+	got := stripExecEnv(config)
+
+	if env := got.AuthInfos[0].AuthInfo.Exec.Env; env != nil {
+		t.Errorf("exec env = %v, want none", env)
+	}
+
+	if got.AuthInfos[0].AuthInfo.Exec.Command != "aws" {
+		t.Errorf("exec command = %q, want aws", got.AuthInfos[0].AuthInfo.Exec.Command)
+	}
+
+	if len(plugin.Env) != 1 {
+		t.Errorf("stripExecEnv() modified its input")
+	}
+
+	if got.AuthInfos[1].AuthInfo.Token != "abc" {
+		t.Errorf("users without exec plugins should be kept as is")
+	}
+}
+
+func TestReplaceExecWithCredentials(t *testing.T) {
+	config := apiv1.Config{
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "exec", AuthInfo: apiv1.AuthInfo{Exec: &apiv1.ExecConfig{
+				Command:         writeTestPlugin(t, `echo '{"kind":"ExecCredential","status":{"token":"abc"}}'`),
+				APIVersion:      "client.authentication.k8s.io/v1",
+				InteractiveMode: apiv1.NeverExecInteractiveMode,
+			}}},
+		},
+	}
+
+	got, err := replaceExecWithCredentials(config, "/nonexistent/config", 10*time.Second)
+	if err != nil {
+		t.Fatalf("replaceExecWithCredentials() error = %v", err)
+	}
+
+	user := got.AuthInfos[0].AuthInfo
+	if user.Exec != nil || user.Token != "abc" {
+		t.Errorf("user = %+v, want token abc without exec plugin", user)
+	}
+
+	// Certificates returned by the plugin replace the certificate files
+	config.AuthInfos[0].AuthInfo.ClientCertificate = "client.crt"
+	config.AuthInfos[0].AuthInfo.ClientKey = "client.key"
+	config.AuthInfos[0].AuthInfo.Exec.Command = writeTestPlugin(t,
+		`echo '{"kind":"ExecCredential","status":{"clientCertificateData":"cert","clientKeyData":"key"}}'`)
+
+	got, err = replaceExecWithCredentials(config, "/nonexistent/config", 10*time.Second)
+	if err != nil {
+		t.Fatalf("replaceExecWithCredentials() error = %v", err)
+	}
+
+	user = got.AuthInfos[0].AuthInfo
+	if string(user.ClientCertificateData) != "cert" || string(user.ClientKeyData) != "key" || user.ClientCertificate != "" || user.ClientKey != "" {
+		t.Errorf("user = %+v, want inline certificate data without files", user)
+	}
+
+	config.AuthInfos[0].AuthInfo.Exec.Command = writeTestPlugin(t, "exit 1")
+	if _, err := replaceExecWithCredentials(config, "/nonexistent/config", 10*time.Second); err == nil {
+		t.Errorf("replaceExecWithCredentials() expected error for failing plugin")
+	}
+}
+
+func TestExportBytes(t *testing.T) {
+	exported := apiv1.Config{
+		AuthInfos: []apiv1.NamedAuthInfo{{Name: "dev", AuthInfo: apiv1.AuthInfo{Token: "secret-token"}}},
+	}
+
+	tests := []struct {
+		name        string
+		terminal    bool
+		showSecrets bool
+		wantSecret  bool
+	}{
+		{name: "pipe", wantSecret: true},
+		{name: "terminal", terminal: true, wantSecret: false},
+		{name: "terminal with --show-secrets", terminal: true, showSecrets: true, wantSecret: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := exportBytes(exported, tt.terminal, tt.showSecrets)
+			if err != nil {
+				t.Fatalf("exportBytes() error = %v", err)
+			}
+
+			if got := strings.Contains(string(b), "secret-token"); got != tt.wantSecret {
+				t.Errorf("exportBytes() = %s, want secret shown %v", b, tt.wantSecret)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/ktr0731/go-fuzzyfinder"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+const (
+	pickerBuiltin = "builtin"
+	pickerAuto    = "auto"
+	pickerFzf     = "fzf"
+	pickerSk      = "sk"
+
+	sortName       = "name"
+	sortRecent     = "recent"
+	sortKubeconfig = "kubeconfig"
+
+	caseSmart       = "smart"
+	caseSensitive   = "sensitive"
+	caseInsensitive = "insensitive"
+)
+
+// nonInteractive is set by --non-interactive to never open the finder.
+var nonInteractive bool
+
+// canPrompt reports whether the finder can be opened, which needs a terminal on stdin.
+var canPrompt = func() bool {
+	return !nonInteractive && term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// matchContexts returns the contexts containing query, compared according to the finder.case
+// setting. Smart matching ignores case unless the query contains an upper case letter.
+func matchContexts(contexts []string, query, mode string) []string {
+	fold := mode == caseInsensitive || mode != caseSensitive && strings.ToLower(query) == query
+
+	var matches []string
+
+	for _, ctx := range contexts {
+		if fold && strings.Contains(strings.ToLower(ctx), strings.ToLower(query)) || strings.Contains(ctx, query) {
+			matches = append(matches, ctx)
+		}
+	}
+
+	return matches
+}
+
+// ambiguousContextError lists the contexts a query matches when it cannot be asked which one
+// is meant. It exits with code 2 so that scripts can tell it apart from other failures.
+func ambiguousContextError(query string, candidates []string) error {
+	var b strings.Builder
+
+	if query == "" {
+		b.WriteString("no context given and not running in a terminal, available contexts:")
+	} else {
+		fmt.Fprintf(&b, "context %q is ambiguous, matching contexts:", query)
+	}
+
+	for _, ctx := range candidates {
+		fmt.Fprintf(&b, "\n  %s", ctx)
+	}
+
+	return cli.Exit(b.String(), 2)
+}
+
+// sortContexts returns the context names of config in the given order: by name, most recently
+// entered first (never entered contexts follow by name), or as listed in the kubeconfig.
+func sortContexts(config apiv1.Config, order string, history contextHistory) ([]string, error) {
+	contexts := make([]string, 0, len(config.Contexts))
+	for _, ctx := range config.Contexts {
+		contexts = append(contexts, ctx.Name)
+	}
+
+	switch order {
+	case "", sortName:
+		slices.Sort(contexts)
+	case sortRecent:
+		slices.SortStableFunc(contexts, func(a, b string) int {
+			// Zero times sort last since later times come first
+			return cmp.Or(history.LastUsed[b].Compare(history.LastUsed[a]), strings.Compare(a, b))
+		})
+	case sortKubeconfig:
+	default:
+		return nil, fmt.Errorf("unsupported finder.sort %q: expected %s, %s or %s", order, sortName, sortRecent, sortKubeconfig)
+	}
+
+	return contexts, nil
+}
+
+// resolvePicker returns the picker to use for the finder.picker setting. External pickers that
+// are not installed fall back to the built-in finder.
+func resolvePicker(picker string) (string, error) {
+	switch picker {
+	case "", pickerBuiltin:
+		return pickerBuiltin, nil
+	case pickerAuto:
+		for _, name := range []string{pickerFzf, pickerSk} {
+			if _, err := lookPath(name); err == nil {
+				return name, nil
+			}
+		}
+
+		return pickerBuiltin, nil
+	case pickerFzf, pickerSk:
+		if _, err := lookPath(picker); err != nil {
+			logf("warning: %s is not installed, using the built-in finder", picker)
+
+			return pickerBuiltin, nil
+		}
+
+		return picker, nil
+	default:
+		return "", fmt.Errorf("unsupported finder.picker %q: expected %s, %s, %s or %s", picker, pickerBuiltin, pickerAuto, pickerFzf, pickerSk)
+	}
+}
+
+// pickerArgs returns the command line arguments for an external picker.
+func pickerArgs(picker string, cfg FinderConfig, query, header string, multi bool) []string {
+	args := []string{"--header", header}
+
+	if query != "" {
+		args = append(args, "--query", query)
+	}
+
+	if multi {
+		args = append(args, "--multi")
+	}
+
+	switch picker {
+	case pickerFzf:
+		switch cfg.Case {
+		case caseSensitive:
+			args = append(args, "+i")
+		case caseInsensitive:
+			args = append(args, "-i")
+		}
+	case pickerSk:
+		switch cfg.Case {
+		case caseSensitive:
+			args = append(args, "--case", "respect")
+		case caseInsensitive:
+			args = append(args, "--case", "ignore")
+		}
+	}
+
+	return args
+}
+
+// externalPick runs an external picker with one item per line on its standard input and
+// returns the items it prints. Exiting without a selection aborts like the built-in finder.
+func externalPick(command string, args []string, items []string) ([]string, error) {
+	var out bytes.Buffer
+
+	cmd := exec.Command(command, args...)
+	cmd.Stdin = strings.NewReader(strings.Join(items, "\n") + "\n")
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// fzf and sk exit with 1 when nothing matches and 130 when interrupted
+			return nil, fuzzyfinder.ErrAbort
+		}
+
+		return nil, fmt.Errorf("failed to run %s: %w", command, err)
+	}
+
+	var selected []string
+
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line != "" && slices.Contains(items, line) {
+			selected = append(selected, line)
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, fuzzyfinder.ErrAbort
+	}
+
+	return selected, nil
+}
+
+// builtinPick selects items, shown as labels, with go-fuzzyfinder.
+func builtinPick(cfg FinderConfig, items, labels []string, query, header string, multi bool, preview func(string) string) ([]string, error) {
+	opts := []fuzzyfinder.Option{fuzzyfinder.WithHeader(header)}
+
+	switch cfg.Case {
+	case "", caseSmart:
+	case caseSensitive:
+		opts = append(opts, fuzzyfinder.WithMode(fuzzyfinder.ModeCaseSensitive))
+	case caseInsensitive:
+		opts = append(opts, fuzzyfinder.WithMode(fuzzyfinder.ModeCaseInsensitive))
+	default:
+		return nil, fmt.Errorf("unsupported finder.case %q: expected %s, %s or %s", cfg.Case, caseSmart, caseSensitive, caseInsensitive)
+	}
+
+	if preview != nil {
+		opts = append(opts, fuzzyfinder.WithPreviewWindow(func(i, _, _ int) string {
+			if i < 0 {
+				return ""
+			}
+
+			return preview(items[i])
+		}))
+	}
+
+	if query != "" {
+		opts = append(opts, fuzzyfinder.WithQuery(query))
+	}
+
+	itemFunc := func(i int) string { return labels[i] }
+
+	if !multi {
+		i, err := fuzzyfinder.Find(items, itemFunc, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{items[i]}, nil
+	}
+
+	indexes, err := fuzzyfinder.FindMulti(items, itemFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]string, len(indexes))
+	for i, index := range indexes {
+		selected[i] = items[index]
+	}
+
+	return selected, nil
+}
+
+// pickContexts lets the user select one, or with multi several, of contexts using the picker
+// configured in the finder section. Contexts are shown as labels, and the preview is only shown
+// by the built-in finder.
+func pickContexts(cfg FinderConfig, contexts, labels []string, query, header string, multi bool, preview func(string) string) ([]string, error) {
+	picker, err := resolvePicker(cfg.Picker)
+	if err != nil {
+		return nil, err
+	}
+
+	header = cmp.Or(cfg.Header, header)
+
+	if picker == pickerBuiltin {
+		return builtinPick(cfg, contexts, labels, query, header, multi, preview)
+	}
+
+	selected, err := externalPick(picker, pickerArgs(picker, cfg, query, header, multi), labels)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, label := range selected {
+		selected[i] = contexts[slices.Index(labels, label)]
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ktr0731/go-fuzzyfinder"
+	"github.com/urfave/cli/v2"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+func TestSortContexts(t *testing.T) {
+	config := apiv1.Config{
+		Contexts: []apiv1.NamedContext{{Name: "staging"}, {Name: "prod"}, {Name: "dev"}, {Name: "local"}},
+	}
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := contextHistory{LastUsed: map[string]time.Time{
+		"prod":    now.Add(-time.Hour),
+		"staging": now,
+	}}
+
+	tests := []struct {
+		order   string
+		want    []string
+		wantErr bool
+	}{
+		{order: "", want: []string{"dev", "local", "prod", "staging"}},
+		{order: sortName, want: []string{"dev", "local", "prod", "staging"}},
+		{order: sortRecent, want: []string{"staging", "prod", "dev", "local"}},
+		{order: sortKubeconfig, want: []string{"staging", "prod", "dev", "local"}},
+		{order: "random", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			got, err := sortContexts(config, tt.order, history)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sortContexts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortContexts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePicker(t *testing.T) {
+	origLookPath := lookPath
+	defer func() { lookPath = origLookPath }()
+
+	installed := map[string]bool{"sk": true}
+	lookPath = func(file string) (string, error) {
+		if installed[file] {
+			return "/usr/bin/" + file, nil
+		}
+
+		return "", exec.ErrNotFound
+	}
+
+	tests := []struct {
+		picker  string
+		want    string
+		wantErr bool
+	}{
+		{picker: "", want: pickerBuiltin},
+		{picker: pickerBuiltin, want: pickerBuiltin},
+		{picker: pickerAuto, want: pickerSk},
+		{picker: pickerSk, want: pickerSk},
+		{picker: pickerFzf, want: pickerBuiltin},
+		{picker: "peco", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.picker, func(t *testing.T) {
+			got, err := resolvePicker(tt.picker)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolvePicker() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("resolvePicker() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickerArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		picker string
+		cfg    FinderConfig
+		query  string
+		multi  bool
+		want   []string
+	}{
+		{
+			name:   "fzf",
+			picker: pickerFzf,
+			cfg:    FinderConfig{Case: caseSmart},
+			want:   []string{"--header", "Contexts"},
+		},
+		{
+			name:   "fzf with query, multi-select and case sensitivity",
+			picker: pickerFzf,
+			cfg:    FinderConfig{Case: caseSensitive},
+			query:  "prod",
+			multi:  true,
+			want:   []string{"--header", "Contexts", "--query", "prod", "--multi", "+i"},
+		},
+		{
+			name:   "sk case insensitive",
+			picker: pickerSk,
+			cfg:    FinderConfig{Case: caseInsensitive},
+			want:   []string{"--header", "Contexts", "--case", "ignore"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pickerArgs(tt.picker, tt.cfg, tt.query, "Contexts", tt.multi); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pickerArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExternalPick(t *testing.T) {
+	items := []string{"dev", "prod", "staging"}
+
+	got, err := externalPick("tail", []string{"-n", "2"}, items)
+	if err != nil {
+		t.Fatalf("externalPick() error = %v", err)
+	}
+
+	if want := []string{"prod", "staging"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("externalPick() = %v, want %v", got, want)
+	}
+
+	if _, err := externalPick("false", nil, items); !errors.Is(err, fuzzyfinder.ErrAbort) {
+		t.Errorf("externalPick() error = %v, want %v", err, fuzzyfinder.ErrAbort)
+	}
+}
+
+func TestMatchContexts(t *testing.T) {
+	contexts := []string{"Prod-EU", "prod-us", "dev"}
+
+	tests := []struct {
+		query string
+		mode  string
+		want  []string
+	}{
+		{query: "prod", mode: caseSmart, want: []string{"Prod-EU", "prod-us"}},
+		{query: "Prod", mode: caseSmart, want: []string{"Prod-EU"}},
+		{query: "prod", mode: caseSensitive, want: []string{"prod-us"}},
+		{query: "PROD", mode: caseInsensitive, want: []string{"Prod-EU", "prod-us"}},
+		{query: "stag", mode: caseSmart, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query+"/"+tt.mode, func(t *testing.T) {
+			if got := matchContexts(contexts, tt.query, tt.mode); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchContexts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindContextsNonInteractive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	writeTestFile(t, path, `apiVersion: v1
+kind: Config
+contexts:
+- name: prod-eu
+  context: {cluster: c, user: u}
+- name: prod-us
+  context: {cluster: c, user: u}
+- name: dev
+  context: {cluster: c, user: u}
+`)
+
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	origCanPrompt, origUserHomeDir := canPrompt, userHomeDir
+	defer func() {
+		canPrompt, userHomeDir, kubeconfigFlag = origCanPrompt, origUserHomeDir, ""
+	}()
+
+	canPrompt = func() bool { return false }
+	userHomeDir = func() (string, error) { return t.TempDir(), nil }
+	kubeconfigFlag = path
+
+	tests := []struct {
+		query    string
+		want     []string
+		wantCode int
+	}{
+		{query: "dev", want: []string{"dev"}},
+		{query: "eu", want: []string{"prod-eu"}},
+		{query: "prod", wantCode: 2},
+		{query: "", wantCode: 2},
+		{query: "staging", wantCode: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := findContexts(tt.query, nil, false, false)
+
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Fatalf("findContexts() error = %v", err)
+				}
+
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("findContexts() = %v, want %v", got, tt.want)
+				}
+
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("findContexts() expected error")
+			}
+
+			code := 1
+
+			var exitErr cli.ExitCoder
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			}
+
+			if code != tt.wantCode {
+				t.Errorf("findContexts() exit code = %d, want %d (%v)", code, tt.wantCode, err)
+			}
+
+			if code == 2 && !strings.Contains(err.Error(), "prod-us") {
+				t.Errorf("findContexts() error should list the candidates, got %v", err)
+			}
+		})
+	}
+}
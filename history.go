@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// contextHistory records when each context was last entered.
+type contextHistory struct {
+	LastUsed map[string]time.Time `json:"last_used"`
+}
+
+// stateDir returns the directory ksw keeps persistent state in,
+// $XDG_STATE_HOME/ksw or ~/.local/state/ksw.
+func stateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "ksw"), nil
+	}
+
+	home, err := userHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "state", "ksw"), nil
+}
+
+// historyPath returns the file context usage is recorded in.
+func historyPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "history.json"), nil
+}
+
+// readHistory loads the context history from path. A missing file is an empty history.
+func readHistory(path string) (contextHistory, error) {
+	history := contextHistory{LastUsed: map[string]time.Time{}}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+
+	if err != nil {
+		return history, err
+	}
+
+	if err := json.Unmarshal(b, &history); err != nil {
+		return history, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if history.LastUsed == nil {
+		history.LastUsed = map[string]time.Time{}
+	}
+
+	return history, nil
+}
+
+// writeHistory saves the context history to path.
+func writeHistory(path string, history contextHistory) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0600)
+}
+
+// loadHistory loads the context history from its default location.
+func loadHistory() (contextHistory, error) {
+	path, err := historyPath()
+	if err != nil {
+		return contextHistory{LastUsed: map[string]time.Time{}}, err
+	}
+
+	return readHistory(path)
+}
+
+// recordContextUse records that contextName was entered at now. Failures are only logged
+// since history is informational and must never prevent entering a context.
+func recordContextUse(contextName string, now time.Time) {
+	path, err := historyPath()
+	if err != nil {
+		return
+	}
+
+	history, err := readHistory(path)
+	if err != nil {
+		logf("warning: failed to read context history: %v", err)
+
+		return
+	}
+
+	history.LastUsed[contextName] = now
+
+	if err := writeHistory(path, history); err != nil {
+		logf("warning: failed to record context history: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordContextUse(t *testing.T) {
+	stateDir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", stateDir)
+
+	first := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	recordContextUse("dev", first)
+	recordContextUse("prod", first)
+	recordContextUse("dev", second)
+
+	path := filepath.Join(stateDir, "ksw", "history.json")
+
+	history, err := readHistory(path)
+	if err != nil {
+		t.Fatalf("readHistory() error = %v", err)
+	}
+
+	if got := history.LastUsed["dev"]; !got.Equal(second) {
+		t.Errorf("dev last used = %v, want %v", got, second)
+	}
+
+	if got := history.LastUsed["prod"]; !got.Equal(first) {
+		t.Errorf("prod last used = %v, want %v", got, first)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat history: %v", err)
+	}
+
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("history permissions = %04o, want 0600", perm)
+	}
+}
+
+func TestReadHistoryMissing(t *testing.T) {
+	history, err := readHistory(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil {
+		t.Fatalf("readHistory() error = %v", err)
+	}
+
+	if history.LastUsed == nil || len(history.LastUsed) != 0 {
+		t.Errorf("readHistory() = %+v, want empty history", history)
+	}
+}
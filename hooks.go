@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// runHook runs a context hook command with the user's shell. Failures are logged
+// rather than returned so a broken hook never prevents entering or leaving a session.
+func runHook(shell, name, command string, env []string) {
+	if command == "" {
+		return
+	}
+
+	cmd := exec.Command(shell, "-c", command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = env
+
+	if err := cmd.Run(); err != nil {
+		logf("warning: %s hook failed: %v", name, err)
+	}
+}
+
+// hookShell returns the shell used to run hooks inside an existing session.
+func hookShell() string {
+	if shell := os.Getenv("KSW_SHELL"); shell != "" {
+		return shell
+	}
+
+	return "/bin/sh"
+}
+
+// contextEnviron returns base with the variables of the previous context removed
+// and the variables of the next context set.
+func contextEnviron(base []string, prev, next map[string]string) []string {
+	env := make([]string, 0, len(base)+len(next))
+
+	for _, kv := range base {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, isPrev := prev[key]; isPrev {
+			continue
+		}
+
+		if _, isNext := next[key]; isNext {
+			continue
+		}
+
+		env = append(env, kv)
+	}
+
+	for _, k := range sortedKeys(next) {
+		env = append(env, k+"="+next[k])
+	}
+
+	return env
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validEnvName reports whether name can be used as a shell variable name.
+func validEnvName(name string) bool {
+	return envNameRegexp.MatchString(name)
+}
+
+// contextEnvScript returns POSIX shell statements that unset the variables of the
+// previous context and export the variables of the next one. Names that are not shell
+// identifiers are skipped since the script is evaluated by the shell.
+func contextEnvScript(prev, next map[string]string) string {
+	var b strings.Builder
+
+	for _, k := range sortedKeys(prev) {
+		if _, ok := next[k]; !ok && validEnvName(k) {
+			fmt.Fprintf(&b, "unset %s\n", k)
+		}
+	}
+
+	for _, k := range sortedKeys(next) {
+		if validEnvName(k) {
+			fmt.Fprintf(&b, "export %s=%s\n", k, shellQuote(next[k]))
+		}
+	}
+
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+// sessionCurrentContext returns the current context recorded in a session kubeconfig.
+func sessionCurrentContext(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	var config apiv1.Config
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return ""
+	}
+
+	return config.CurrentContext
+}
+
+// emitContextEnv hands the environment changes of a context switch to the shell integration,
+// which sources the file named by KSW_ENV_FILE after ksw exits.
+func emitContextEnv(prev, next map[string]string) error {
+	if len(prev) == 0 && len(next) == 0 {
+		return nil
+	}
+
+	envFile := os.Getenv("KSW_ENV_FILE")
+	if envFile == "" {
+		logf("context environment variables were not applied; enable shell integration with: eval \"$(ksw init %s)\"",
+			shellName(hookShell()))
+
+		return nil
+	}
+
+	return os.WriteFile(envFile, []byte(contextEnvScript(prev, next)), 0600)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestContextEnvScript(t *testing.T) {
+	prev := map[string]string{"AWS_PROFILE": "prod", "ARGOCD_SERVER": "argo.prod", "$(id)": "x"}
+	next := map[string]string{"AWS_PROFILE": "dev", "HELM_NAMESPACE": "it's", "X;rm -rf ~": "y"}
+
+	got := contextEnvScript(prev, next)
+	want := "unset ARGOCD_SERVER\n" +
+		"export AWS_PROFILE='dev'\n" +
+		"export HELM_NAMESPACE='it'\\''s'\n"
+
+	if got != want {
+		t.Errorf("contextEnvScript() = %q, want %q", got, want)
+	}
+}
+
+func TestContextEnviron(t *testing.T) {
+	base := []string{"PATH=/bin", "AWS_PROFILE=prod", "ARGOCD_SERVER=argo.prod"}
+	prev := map[string]string{"AWS_PROFILE": "prod", "ARGOCD_SERVER": "argo.prod"}
+	next := map[string]string{"AWS_PROFILE": "dev"}
+
+	got := contextEnviron(base, prev, next)
+	want := []string{"PATH=/bin", "AWS_PROFILE=dev"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("contextEnviron() = %v, want %v", got, want)
+	}
+}
+
+func TestEmitContextEnv(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "env")
+	t.Setenv("KSW_ENV_FILE", envFile)
+
+	if err := emitContextEnv(nil, map[string]string{"AWS_PROFILE": "dev"}); err != nil {
+		t.Fatalf("emitContextEnv() error = %v", err)
+	}
+
+	got, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatalf("Failed to read env file: %v", err)
+	}
+
+	if string(got) != "export AWS_PROFILE='dev'\n" {
+		t.Errorf("emitContextEnv() wrote %q", got)
+	}
+}
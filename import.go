@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// renameEntities renames contexts, clusters and users of config and updates context references and the current context.
+func renameEntities(config apiv1.Config, contexts, clusters, users []KubeconfigRename) apiv1.Config {
+	renamed := config
+
+	renamed.Contexts = make([]apiv1.NamedContext, len(config.Contexts))
+	for i, x := range config.Contexts {
+		renamed.Contexts[i] = apiv1.NamedContext{
+			Name:    renameTarget(contexts, x.Name),
+			Context: renameContextRefs(x.Context, clusters, users),
+		}
+	}
+
+	renamed.Clusters = make([]apiv1.NamedCluster, len(config.Clusters))
+	for i, x := range config.Clusters {
+		renamed.Clusters[i] = apiv1.NamedCluster{Name: renameTarget(clusters, x.Name), Cluster: x.Cluster}
+	}
+
+	renamed.AuthInfos = make([]apiv1.NamedAuthInfo, len(config.AuthInfos))
+	for i, x := range config.AuthInfos {
+		renamed.AuthInfos[i] = apiv1.NamedAuthInfo{Name: renameTarget(users, x.Name), AuthInfo: x.AuthInfo}
+	}
+
+	renamed.CurrentContext = renameTarget(contexts, config.CurrentContext)
+
+	return renamed
+}
+
+// prefixNames returns renames that put prefix in front of every name.
+func prefixNames(names []string, prefix string) []KubeconfigRename {
+	renames := make([]KubeconfigRename, 0, len(names))
+	for _, name := range names {
+		renames = append(renames, KubeconfigRename{From: name, To: prefix + name})
+	}
+
+	return renames
+}
+
+// prefixImported puts prefix in front of every context, cluster and user name of an imported config.
+func prefixImported(imported apiv1.Config, prefix string) apiv1.Config {
+	if prefix == "" {
+		return imported
+	}
+
+	var contexts, clusters, users []string
+
+	for _, x := range imported.Contexts {
+		contexts = append(contexts, x.Name)
+	}
+
+	for _, x := range imported.Clusters {
+		clusters = append(clusters, x.Name)
+	}
+
+	for _, x := range imported.AuthInfos {
+		users = append(users, x.Name)
+	}
+
+	return renameEntities(imported, prefixNames(contexts, prefix), prefixNames(clusters, prefix), prefixNames(users, prefix))
+}
+
+// uniqueName returns name, or name with the lowest numeric suffix starting at 2 that is not taken.
+func uniqueName(name string, taken map[string]bool) string {
+	if !taken[name] {
+		return name
+	}
+
+	for i := 2; ; i++ {
+		if candidate := name + "-" + strconv.Itoa(i); !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// conflictRenames returns renames for imported entities whose name already exists in orig with a different body.
+func conflictRenames[V any](orig, imported map[string]V, names []string) []KubeconfigRename {
+	taken := make(map[string]bool, len(orig)+len(imported))
+	for name := range orig {
+		taken[name] = true
+	}
+
+	for name := range imported {
+		taken[name] = true
+	}
+
+	var renames []KubeconfigRename
+
+	for _, name := range names {
+		existing, ok := orig[name]
+		if !ok || reflect.DeepEqual(existing, imported[name]) {
+			continue
+		}
+
+		to := uniqueName(name, taken)
+		taken[to] = true
+		renames = append(renames, KubeconfigRename{From: name, To: to})
+	}
+
+	return renames
+}
+
+// renameImportConflicts renames imported entities that would overwrite a different entity of the
+// same name in orig. Clusters and users are resolved first so that contexts are compared with
+// their references already pointing at the renamed entities.
+func renameImportConflicts(orig, imported apiv1.Config) apiv1.Config {
+	var clusterNames, userNames, contextNames []string
+
+	for _, x := range imported.Clusters {
+		clusterNames = append(clusterNames, x.Name)
+	}
+
+	for _, x := range imported.AuthInfos {
+		userNames = append(userNames, x.Name)
+	}
+
+	clusters := conflictRenames(clustersMap(orig.Clusters), clustersMap(imported.Clusters), clusterNames)
+	users := conflictRenames(usersMap(orig.AuthInfos), usersMap(imported.AuthInfos), userNames)
+	imported = renameEntities(imported, nil, clusters, users)
+
+	for _, x := range imported.Contexts {
+		contextNames = append(contextNames, x.Name)
+	}
+
+	contexts := conflictRenames(contextsMap(orig.Contexts), contextsMap(imported.Contexts), contextNames)
+
+	return renameEntities(imported, contexts, nil, nil)
+}
+
+// resolveImportedPaths makes the relative file references of an imported or exported config
+// absolute, since they are relative to source and would break once moved to another kubeconfig.
+func resolveImportedPaths(imported apiv1.Config, source string) apiv1.Config {
+	clusters := make([]apiv1.NamedCluster, len(imported.Clusters))
+	for i, x := range imported.Clusters {
+		x.Cluster.CertificateAuthority = resolveKubeconfigPath(source, x.Cluster.CertificateAuthority)
+		clusters[i] = x
+	}
+
+	users := make([]apiv1.NamedAuthInfo, len(imported.AuthInfos))
+	for i, x := range imported.AuthInfos {
+		x.AuthInfo.ClientCertificate = resolveKubeconfigPath(source, x.AuthInfo.ClientCertificate)
+		x.AuthInfo.ClientKey = resolveKubeconfigPath(source, x.AuthInfo.ClientKey)
+		x.AuthInfo.TokenFile = resolveKubeconfigPath(source, x.AuthInfo.TokenFile)
+		users[i] = x
+	}
+
+	imported.Clusters = clusters
+	imported.AuthInfos = users
+
+	return imported
+}
+
+func importAction(c *cli.Context) error {
+	source := c.Args().First()
+	if source == "" {
+		return fmt.Errorf("kubeconfig file to import required")
+	}
+
+	imported, err := readKubeconfig(source)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+
+	originalPath := getOriginalKubeconfigPath()
+
+	orig, err := readKubeconfig(originalPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read original kubeconfig: %w", err)
+	}
+
+	imported = resolveImportedPaths(imported, source)
+	imported = prefixImported(imported, c.String("prefix"))
+
+	if c.Bool("rename-conflicts") {
+		imported = renameImportConflicts(orig, imported)
+	}
+
+	// Deletions are never proposed: entities missing from the imported file are simply kept
+	diff := computeKubeconfigDiff(orig, imported, true)
+	if !diff.HasChanges() {
+		fmt.Printf("Nothing to import: %s adds nothing to %s.\n", source, originalPath)
+
+		return nil
+	}
+
+	fmt.Printf("Importing %s into %s.\n", source, originalPath)
+
+	selected, err := pickChanges(diff, "Select which changes you want to import:")
+	if err != nil {
+		return fmt.Errorf("error during interactive selection: %w", err)
+	}
+
+	if !selected.HasChanges() {
+		return nil
+	}
+
+	applied, err := applyChangesToOriginal(originalPath, selected)
+	if err != nil || !applied {
+		return err
+	}
+
+	fmt.Println("Selected changes successfully imported.")
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+func TestPrefixImported(t *testing.T) {
+	imported := apiv1.Config{
+		CurrentContext: "dev",
+		Contexts: []apiv1.NamedContext{
+			{Name: "dev", Context: apiv1.Context{Cluster: "dev-cluster", AuthInfo: "dev-user"}},
+		},
+		Clusters:  []apiv1.NamedCluster{{Name: "dev-cluster", Cluster: apiv1.Cluster{Server: "https://dev.example.com"}}},
+		AuthInfos: []apiv1.NamedAuthInfo{{Name: "dev-user", AuthInfo: apiv1.AuthInfo{Token: "token"}}},
+	}
+
+	got := prefixImported(imported, "acme-")
+
+	want := apiv1.Config{
+		CurrentContext: "acme-dev",
+		Contexts: []apiv1.NamedContext{
+			{Name: "acme-dev", Context: apiv1.Context{Cluster: "acme-dev-cluster", AuthInfo: "acme-dev-user"}},
+		},
+		Clusters:  []apiv1.NamedCluster{{Name: "acme-dev-cluster", Cluster: apiv1.Cluster{Server: "https://dev.example.com"}}},
+		AuthInfos: []apiv1.NamedAuthInfo{{Name: "acme-dev-user", AuthInfo: apiv1.AuthInfo{Token: "token"}}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("prefixImported() = %+v, want %+v", got, want)
+	}
+
+	if imported.Contexts[0].Name != "dev" {
+		t.Errorf("prefixImported() modified its input")
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken map[string]bool
+		want  string
+	}{
+		{name: "prod", taken: map[string]bool{}, want: "prod"},
+		{name: "prod", taken: map[string]bool{"prod": true}, want: "prod-2"},
+		{name: "prod", taken: map[string]bool{"prod": true, "prod-2": true}, want: "prod-3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := uniqueName(tt.name, tt.taken); got != tt.want {
+				t.Errorf("uniqueName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenameImportConflicts(t *testing.T) {
+	orig := apiv1.Config{
+		Contexts: []apiv1.NamedContext{
+			{Name: "prod", Context: apiv1.Context{Cluster: "prod", AuthInfo: "admin"}},
+			{Name: "shared", Context: apiv1.Context{Cluster: "shared", AuthInfo: "admin"}},
+		},
+		Clusters: []apiv1.NamedCluster{
+			{Name: "prod", Cluster: apiv1.Cluster{Server: "https://prod-a.example.com"}},
+			{Name: "shared", Cluster: apiv1.Cluster{Server: "https://shared.example.com"}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "admin", AuthInfo: apiv1.AuthInfo{Token: "token-a"}},
+		},
+	}
+
+	imported := apiv1.Config{
+		Contexts: []apiv1.NamedContext{
+			{Name: "prod", Context: apiv1.Context{Cluster: "prod", AuthInfo: "admin"}},
+			{Name: "shared", Context: apiv1.Context{Cluster: "shared", AuthInfo: "viewer"}},
+			{Name: "new", Context: apiv1.Context{Cluster: "shared", AuthInfo: "viewer"}},
+		},
+		Clusters: []apiv1.NamedCluster{
+			{Name: "prod", Cluster: apiv1.Cluster{Server: "https://prod-b.example.com"}},
+			{Name: "shared", Cluster: apiv1.Cluster{Server: "https://shared.example.com"}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "admin", AuthInfo: apiv1.AuthInfo{Token: "token-b"}},
+			{Name: "viewer", AuthInfo: apiv1.AuthInfo{Token: "token-c"}},
+		},
+	}
+
+	got := renameImportConflicts(orig, imported)
+
+	want := apiv1.Config{
+		Contexts: []apiv1.NamedContext{
+			{Name: "prod-2", Context: apiv1.Context{Cluster: "prod-2", AuthInfo: "admin-2"}},
+			{Name: "shared-2", Context: apiv1.Context{Cluster: "shared", AuthInfo: "viewer"}},
+			{Name: "new", Context: apiv1.Context{Cluster: "shared", AuthInfo: "viewer"}},
+		},
+		Clusters: []apiv1.NamedCluster{
+			{Name: "prod-2", Cluster: apiv1.Cluster{Server: "https://prod-b.example.com"}},
+			{Name: "shared", Cluster: apiv1.Cluster{Server: "https://shared.example.com"}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "admin-2", AuthInfo: apiv1.AuthInfo{Token: "token-b"}},
+			{Name: "viewer", AuthInfo: apiv1.AuthInfo{Token: "token-c"}},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("renameImportConflicts() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	diff := computeKubeconfigDiff(orig, got, true)
+	if len(diff.ContextsModified) != 0 || len(diff.ClustersModified) != 0 || len(diff.UsersModified) != 0 {
+		t.Errorf("expected no modifications after renaming conflicts, got %+v", diff)
+	}
+}
+
+func TestResolveImportedPaths(t *testing.T) {
+	imported := apiv1.Config{
+		Clusters: []apiv1.NamedCluster{
+			{Name: "c", Cluster: apiv1.Cluster{CertificateAuthority: "ca.crt"}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "u", AuthInfo: apiv1.AuthInfo{ClientCertificate: "certs/client.crt", ClientKey: "/abs/client.key"}},
+		},
+	}
+
+	got := resolveImportedPaths(imported, "/home/user/Downloads/kubeconfig.yaml")
+
+	if ca := got.Clusters[0].Cluster.CertificateAuthority; ca != "/home/user/Downloads/ca.crt" {
+		t.Errorf("certificate-authority = %q", ca)
+	}
+
+	if cert := got.AuthInfos[0].AuthInfo.ClientCertificate; cert != "/home/user/Downloads/certs/client.crt" {
+		t.Errorf("client-certificate = %q", cert)
+	}
+
+	if key := got.AuthInfos[0].AuthInfo.ClientKey; key != "/abs/client.key" {
+		t.Errorf("client-key = %q", key)
+	}
+
+	if imported.Clusters[0].Cluster.CertificateAuthority != "ca.crt" {
+		t.Errorf("resolveImportedPaths() modified its input")
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"slices"
+	"strings"
+	"time"
 
 	"github.com/ghodss/yaml"
-	"github.com/ktr0731/go-fuzzyfinder"
 	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
 )
 
@@ -45,36 +45,94 @@ func minifyConfig(c apiv1.Config, contextName string) (*apiv1.Config, error) {
 	return &config, nil
 }
 
+// kubeconfigFlag is the original kubeconfig given with --kubeconfig, if any.
+var kubeconfigFlag string
+
+// getOriginalKubeconfigPath returns the original kubeconfig: the --kubeconfig flag,
+// KSW_KUBECONFIG_ORIGINAL inside a session, KUBECONFIG, or ~/.kube/config, in that order.
 func getOriginalKubeconfigPath() string {
-	var kubeconfigPath string // TODO add condition to use original kubeconfig from cli flags
+	if kubeconfigFlag != "" {
+		return kubeconfigFlag
+	}
+
 	if path := os.Getenv("KSW_KUBECONFIG_ORIGINAL"); path != "" {
-		kubeconfigPath = path
-	} else if path := os.Getenv("KUBECONFIG"); path != "" {
-		kubeconfigPath = path
-	} else {
-		kubeconfigPath = filepath.Join(os.Getenv("HOME"), ".kube", "config")
+		return path
 	}
 
-	return kubeconfigPath
-}
+	if path := os.Getenv("KUBECONFIG"); path != "" {
+		return path
+	}
 
-func generateKubeconfig(sourcePath string, contextName string) ([]byte, error) {
-	sourceBytes, err := os.ReadFile(sourcePath)
+	home, err := userHomeDir()
 	if err != nil {
-		return nil, err
+		home = os.Getenv("HOME")
 	}
 
+	return filepath.Join(home, ".kube", "config")
+}
+
+// absPathList makes every path of a KUBECONFIG style list absolute, so that sessions
+// keep finding the original kubeconfig after changing directory.
+func absPathList(list string) (string, error) {
+	paths := filepath.SplitList(list)
+
+	for i, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+
+		paths[i] = abs
+	}
+
+	return strings.Join(paths, string(filepath.ListSeparator)), nil
+}
+
+// readKubeconfig loads and parses a single kubeconfig file, decrypting it if needed.
+func readKubeconfig(path string) (apiv1.Config, error) {
+	config, _, err := readKubeconfigWithEncryption(path)
+
+	return config, err
+}
+
+// readKubeconfigWithEncryption loads and parses a single kubeconfig file and reports how it was encrypted.
+func readKubeconfigWithEncryption(path string) (apiv1.Config, kubeconfigEncryption, error) {
 	var config apiv1.Config
 
+	sourceBytes, enc, err := readKubeconfigBytes(path)
+	if err != nil {
+		return config, enc, err
+	}
+
 	if err := yaml.Unmarshal(sourceBytes, &config); err != nil {
+		return config, enc, err
+	}
+
+	return config, enc, nil
+}
+
+// shouldMinify reports whether the session kubeconfig of contextName from a source encrypted
+// as enc is minified. Encrypted sources are always minified so only the selected context leaves
+// the encrypted file.
+func shouldMinify(cfg KswConfig, enc kubeconfigEncryption, contextName string) bool {
+	return cfg.minify(contextName) || enc != encryptionNone
+}
+
+func generateKubeconfig(sourcePath string, contextName string) ([]byte, error) {
+	config, enc, err := readKubeconfigWithEncryption(sourcePath)
+	if err != nil {
 		return nil, err
 	}
 
-	cfg := loadConfig()
+	return sessionKubeconfig(config, enc, contextName, loadConfig())
+}
 
+// sessionKubeconfig returns the session kubeconfig of contextName from config, the parsed
+// original kubeconfig encrypted as enc.
+func sessionKubeconfig(config apiv1.Config, enc kubeconfigEncryption, contextName string, cfg KswConfig) ([]byte, error) {
 	var outputConfig *apiv1.Config
 
-	if cfg.Kubeconfig.Minify {
+	if shouldMinify(cfg, enc, contextName) {
 		miniConfig, err := minifyConfig(config, contextName)
 		if err != nil {
 			return nil, err
@@ -107,15 +165,30 @@ func generateKubeconfig(sourcePath string, contextName string) ([]byte, error) {
 	return bytes, nil
 }
 
-func listContexts(path string) ([]string, error) {
-	sourceBytes, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+// setContextNamespace sets the namespace of contextName in a session kubeconfig.
+// An empty namespace leaves the kubeconfig unchanged.
+func setContextNamespace(kubeconfig []byte, contextName, namespace string) ([]byte, error) {
+	if namespace == "" {
+		return kubeconfig, nil
 	}
 
 	var config apiv1.Config
+	if err := yaml.Unmarshal(kubeconfig, &config); err != nil {
+		return nil, err
+	}
 
-	if err := yaml.Unmarshal(sourceBytes, &config); err != nil {
+	for i := range config.Contexts {
+		if config.Contexts[i].Name == contextName {
+			config.Contexts[i].Context.Namespace = namespace
+		}
+	}
+
+	return yaml.Marshal(config)
+}
+
+func listContexts(path string) ([]string, error) {
+	config, err := readKubeconfig(path)
+	if err != nil {
 		return nil, err
 	}
 
@@ -128,35 +201,126 @@ func listContexts(path string) ([]string, error) {
 	return contexts, nil
 }
 
-func findContext(query string) (string, error) {
-	kubeconfigPath := getOriginalKubeconfigPath()
+// contextPreview describes a context for the fuzzy finder preview window, followed by
+// its minified kubeconfig with secrets redacted unless showSecrets is set.
+func contextPreview(config apiv1.Config, contextName string, now time.Time, showSecrets bool) string {
+	ctx, ok := contextsMap(config.Contexts)[contextName]
+	if !ok {
+		return ""
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Context:   %s\n", contextName)
+	fmt.Fprintf(&b, "Cluster:   %s\n", ctx.Cluster)
+
+	if cluster, ok := clustersMap(config.Clusters)[ctx.Cluster]; ok {
+		fmt.Fprintf(&b, "Server:    %s\n", cluster.Server)
+	}
+
+	fmt.Fprintf(&b, "User:      %s\n", ctx.AuthInfo)
+
+	if ctx.Namespace != "" {
+		fmt.Fprintf(&b, "Namespace: %s\n", ctx.Namespace)
+	}
+
+	if expiry, ok := contextCredentialExpiry(config, contextName); ok {
+		fmt.Fprintf(&b, "Expires:   %s\n", formatExpiry(expiry, now))
+	}
 
-	contexts, err := listContexts(kubeconfigPath)
+	if mini, err := minifyConfig(config, contextName); err == nil {
+		if rendered, err := renderConfig(*mini, showSecrets); err == nil {
+			fmt.Fprintf(&b, "\n%s", rendered)
+		}
+	}
+
+	return b.String()
+}
+
+// findContext returns the context named query, or lets the user select one in the finder.
+func findContext(query string, tags []string, showSecrets bool) (string, error) {
+	contexts, err := findContexts(query, tags, false, showSecrets)
 	if err != nil {
 		return "", err
 	}
 
-	slices.Sort(contexts)
+	return contexts[0], nil
+}
+
+// findContexts returns the context named query or the only context containing it, or lets the
+// user select contexts in the finder, several of them with multi. Contexts are limited to those
+// matching the tag filters, given as tags or as "tag:" words in the query. Without a terminal
+// to open the finder in, an ambiguous query is an error listing the matching contexts.
+func findContexts(query string, tags []string, multi, showSecrets bool) ([]string, error) {
+	kubeconfigPath := getOriginalKubeconfigPath()
+
+	config, err := readKubeconfig(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := loadConfig()
+	finder := cfg.Finder
+
+	history, err := loadHistory()
+	if err != nil && finder.Sort == sortRecent {
+		logf("warning: failed to read context history: %v", err)
+	}
+
+	contexts, err := sortContexts(config, finder.Sort, history)
+	if err != nil {
+		return nil, err
+	}
+
+	contexts = cfg.allowedContexts(contexts)
+	if len(contexts) == 0 {
+		return nil, fmt.Errorf("no context matches allowed_contexts")
+	}
+
+	query, filters := splitTagQuery(query)
+	for _, tag := range tags {
+		filters = append(filters, parseTagFilter(tag))
+	}
+
+	contexts = filterContextsByTags(cfg, contexts, filters)
+	if len(contexts) == 0 {
+		return nil, fmt.Errorf("no context matches the tag filters")
+	}
 
 	// Try exact match first
 	for _, ctx := range contexts {
 		if ctx == query {
-			return ctx, nil
+			return []string{ctx}, nil
 		}
 	}
 
-	// Otherwise fuzzy finder
-	opts := []fuzzyfinder.Option{
-		fuzzyfinder.WithHeader(fmt.Sprintf("Using contexts from %s", kubeconfigPath)),
+	candidates := matchContexts(contexts, query, finder.Case)
+
+	// A query or tag filter matching a single context needs no finder
+	if (query != "" || len(filters) > 0) && len(candidates) == 1 {
+		return candidates, nil
 	}
-	if query != "" {
-		opts = append(opts, fuzzyfinder.WithQuery(query))
+
+	if !canPrompt() {
+		switch len(candidates) {
+		case 0:
+			return nil, fmt.Errorf("no context matches %q", query)
+		case 1:
+			return candidates, nil
+		default:
+			return nil, ambiguousContextError(query, candidates)
+		}
 	}
 
-	i, err := fuzzyfinder.Find(contexts, func(i int) string { return contexts[i] }, opts...)
-	if err != nil {
-		return "", err
+	// Otherwise fuzzy finder
+	header := fmt.Sprintf("Using contexts from %s", kubeconfigPath)
+
+	labels := make([]string, len(contexts))
+	for i, ctx := range contexts {
+		labels[i] = contextLabel(cfg, ctx)
 	}
 
-	return contexts[i], nil
+	return pickContexts(finder, contexts, labels, query, header, multi, func(name string) string {
+		return contextPreview(config, name, time.Now(), showSecrets)
+	})
 }
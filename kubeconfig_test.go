@@ -307,6 +307,33 @@ users:
 	}
 }
 
+func TestShouldMinify(t *testing.T) {
+	off := false
+	cfg := KswConfig{
+		Kubeconfig: KubeconfigConfig{Minify: true},
+		Contexts:   map[string]ContextConfig{"dev": {Minify: &off}},
+	}
+
+	tests := []struct {
+		name        string
+		enc         kubeconfigEncryption
+		contextName string
+		want        bool
+	}{
+		{name: "minify setting", contextName: "prod", want: true},
+		{name: "context override", contextName: "dev", want: false},
+		{name: "encrypted source", enc: encryptionAge, contextName: "dev", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldMinify(cfg, tt.enc, tt.contextName); got != tt.want {
+				t.Errorf("shouldMinify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetOriginalKubeconfigPath(t *testing.T) {
 	// Save original env vars
 	origKswOriginal := os.Getenv("KSW_KUBECONFIG_ORIGINAL")
@@ -328,7 +355,17 @@ func TestGetOriginalKubeconfigPath(t *testing.T) {
 		expectedContains string
 		setKswOriginal   bool
 		setKubeconfig    bool
+		flag             string
 	}{
+		{
+			name:             "--kubeconfig flag takes precedence",
+			kswOriginal:      "/custom/ksw/config",
+			setKswOriginal:   true,
+			kubeconfig:       "/custom/kube/config",
+			setKubeconfig:    true,
+			flag:             "/flag/config",
+			expectedContains: "/flag/config",
+		},
 		{
 			name:             "KSW_KUBECONFIG_ORIGINAL set",
 			kswOriginal:      "/custom/ksw/config",
@@ -368,6 +405,9 @@ func TestGetOriginalKubeconfigPath(t *testing.T) {
 				_ = os.Setenv("HOME", tt.home)
 			}
 
+			kubeconfigFlag = tt.flag
+			defer func() { kubeconfigFlag = "" }()
+
 			got := getOriginalKubeconfigPath()
 			if got != tt.expectedContains {
 				t.Errorf("getOriginalKubeconfigPath() = %v, want %v", got, tt.expectedContains)
@@ -375,3 +415,22 @@ func TestGetOriginalKubeconfigPath(t *testing.T) {
 		})
 	}
 }
+
+func TestAbsPathList(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working dir: %v", err)
+	}
+
+	list := "config" + string(filepath.ListSeparator) + "/etc/kube/config"
+	want := filepath.Join(wd, "config") + string(filepath.ListSeparator) + "/etc/kube/config"
+
+	got, err := absPathList(list)
+	if err != nil {
+		t.Fatalf("absPathList() error = %v", err)
+	}
+
+	if got != want {
+		t.Errorf("absPathList() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/urfave/cli/v2"
+)
+
+// projectConfigName is the file name of project-local config files.
+const projectConfigName = ".ksw.yaml"
+
+// originDefault is the origin of values that no config layer sets.
+const originDefault = "default"
+
+var workingDir = os.Getwd
+
+// projectConfigKeys are the settings a project .ksw.yaml may set. Hooks, env and file locations are
+// left out since a cloned repository could otherwise run commands or redirect files.
+var projectConfigKeys = []string{"context", "namespace", "allowed_contexts"}
+
+// resolvedConfig is the effective configuration together with where its values came from.
+type resolvedConfig struct {
+	Config KswConfig
+	// Files lists the config files that were applied, from lowest to highest precedence.
+	Files []string
+	// Origins maps dotted keys to the file or environment variable that set them.
+	Origins map[string]string
+}
+
+// origin returns where the value of a dotted key came from.
+func (r resolvedConfig) origin(key string) string {
+	if origin, ok := r.Origins[key]; ok {
+		return origin
+	}
+
+	return originDefault
+}
+
+// clone returns a copy of c that shares no maps with it.
+func (c KswConfig) clone() KswConfig {
+	c.Contexts = maps.Clone(c.Contexts)
+
+	return c
+}
+
+// findProjectConfig returns the nearest .ksw.yaml in dir or one of its parents, skipping the
+// userConfigs since ~/.ksw.yaml is a user config file even when another one is in use. It
+// returns "" when there is none.
+func findProjectConfig(dir string, userConfigs []string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	for {
+		path := filepath.Join(dir, projectConfigName)
+		if _, err := os.Stat(path); err == nil && !slices.Contains(userConfigs, path) {
+			return path
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+
+		dir = parent
+	}
+}
+
+// isProjectConfig reports whether path is read as a project config, which is any .ksw.yaml
+// other than the user config files.
+func isProjectConfig(path string) bool {
+	if filepath.Base(path) != projectConfigName {
+		return false
+	}
+
+	home, err := userHomeDir()
+	if err != nil {
+		home = ""
+	}
+
+	abs, err := filepath.Abs(path)
+
+	return err != nil || !slices.Contains(userConfigPaths(home, os.LookupEnv), abs)
+}
+
+// configEnvVar returns the environment variable that overrides a dotted config key,
+// such as KSW_CHECK_TIMEOUT for check.timeout.
+func configEnvVar(key string) string {
+	return "KSW_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// configLeafKeys returns the dotted keys of every scalar setting of t, leaving out maps
+// such as contexts whose keys are chosen by the user.
+func configLeafKeys(prefix string, t reflect.Type) []string {
+	if t == durationType || t.Kind() != reflect.Struct {
+		return []string{prefix}
+	}
+
+	var keys []string
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		name := jsonFieldName(field)
+		if name == "" || field.Type.Kind() == reflect.Map || field.Type.Kind() == reflect.Slice {
+			continue
+		}
+
+		keys = append(keys, configLeafKeys(joinKey(prefix, name), field.Type)...)
+	}
+
+	return keys
+}
+
+// configField returns the settable field of v, a struct value, at a dotted key.
+func configField(v reflect.Value, key string) (reflect.Value, bool) {
+	for _, name := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct || v.Type() == durationType {
+			return reflect.Value{}, false
+		}
+
+		found := false
+
+		for i := range v.NumField() {
+			if jsonFieldName(v.Type().Field(i)) == name {
+				v = v.Field(i)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return reflect.Value{}, false
+		}
+	}
+
+	return v, true
+}
+
+// parseConfigValue converts s to a value of the setting type t.
+func parseConfigValue(t reflect.Type, s string) (reflect.Value, error) {
+	if t == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid duration %q", s)
+		}
+
+		return reflect.ValueOf(Duration(d)), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("expected true or false, got %q", s)
+		}
+
+		return reflect.ValueOf(b).Convert(t), nil
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("expected an integer, got %q", s)
+		}
+
+		return reflect.ValueOf(n).Convert(t), nil
+	case reflect.String:
+		return reflect.ValueOf(s).Convert(t), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("unsupported setting type %s", t)
+	}
+}
+
+// applyEnvOverrides sets the config values named by KSW_* environment variables and
+// returns the keys it set.
+func applyEnvOverrides(cfg *KswConfig, lookupEnv func(string) (string, bool)) ([]string, error) {
+	var (
+		keys []string
+		errs []error
+	)
+
+	v := reflect.ValueOf(cfg).Elem()
+
+	for _, key := range configLeafKeys("", v.Type()) {
+		name := configEnvVar(key)
+
+		s, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		field, _ := configField(v, key)
+
+		value, err := parseConfigValue(field.Type(), s)
+		if err != nil {
+			errs = append(errs, ConfigError{File: "$" + name, Message: err.Error()})
+
+			continue
+		}
+
+		field.Set(value)
+
+		keys = append(keys, key)
+	}
+
+	return keys, errors.Join(errs...)
+}
+
+// resolveConfig layers the user config file from home, the nearest project .ksw.yaml above dir
+// and KSW_* environment variables on top of the defaults, in increasing order of precedence.
+// The project config may only set projectConfigKeys, and is only applied once it is allowed with
+// 'ksw allow'. Invalid layers are skipped, and their problems are returned together.
+func resolveConfig(home, dir string, lookupEnv func(string) (string, bool)) (resolvedConfig, error) {
+	resolved := resolvedConfig{Config: defaultConfig(), Origins: make(map[string]string)}
+
+	var files []string
+
+	userConfig := configFilePath(home, lookupEnv)
+	if userConfig != "" {
+		files = append(files, userConfig)
+	}
+
+	if dir != "" {
+		if project := findProjectConfig(dir, userConfigPaths(home, lookupEnv)); project != "" {
+			files = append(files, project)
+		}
+	}
+
+	var errs []error
+
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		project := file != userConfig
+
+		cfg, keys, err := decodeConfigLayer(resolved.Config, file, b, project)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		if project && !projectAllowed(file, b) {
+			errs = append(errs, ConfigError{File: file, Message: "not allowed, run 'ksw allow' to apply it"})
+
+			continue
+		}
+
+		resolved.Config = cfg
+		resolved.Files = append(resolved.Files, file)
+
+		for _, key := range keys {
+			resolved.Origins[key] = file
+		}
+	}
+
+	keys, err := applyEnvOverrides(&resolved.Config, lookupEnv)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, key := range keys {
+		resolved.Origins[key] = "$" + configEnvVar(key)
+	}
+
+	return resolved, errors.Join(errs...)
+}
+
+// configValue is a dotted key with its formatted value.
+type configValue struct {
+	Key   string
+	Value string
+}
+
+// configValues flattens v, a config struct, into dotted keys and formatted values.
+// Map entries are listed in key order.
+func configValues(prefix string, v reflect.Value) []configValue {
+	if v.Type() == durationType {
+		return []configValue{{Key: prefix, Value: time.Duration(v.Int()).String()}}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+
+		return configValues(prefix, v.Elem())
+	case reflect.Struct:
+		var values []configValue
+
+		for i := range v.NumField() {
+			if name := jsonFieldName(v.Type().Field(i)); name != "" {
+				values = append(values, configValues(joinKey(prefix, name), v.Field(i))...)
+			}
+		}
+
+		return values
+	case reflect.Map:
+		var values []configValue
+
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+
+		for _, k := range keys {
+			values = append(values, configValues(joinKey(prefix, k.String()), v.MapIndex(k))...)
+		}
+
+		return values
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range v.Len() {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+
+		return []configValue{{Key: prefix, Value: "[" + strings.Join(items, ", ") + "]"}}
+	default:
+		return []configValue{{Key: prefix, Value: fmt.Sprint(v.Interface())}}
+	}
+}
+
+// configViewAction prints the effective configuration, or with --resolved every setting
+// together with the file or environment variable it came from. Env values are redacted
+// unless --show-secrets is given.
+func configViewAction(c *cli.Context) error {
+	resolved, err := loadResolvedConfig()
+	if err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			logf("config error: %s", line)
+		}
+	}
+
+	if !c.Bool("show-secrets") {
+		resolved.Config = redactKswConfig(resolved.Config)
+	}
+
+	if !c.Bool("resolved") {
+		b, err := yaml.Marshal(resolved.Config)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(string(b))
+
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "KEY\tVALUE\tORIGIN")
+
+	for _, v := range configValues("", reflect.ValueOf(resolved.Config)) {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, v.Value, resolved.origin(v.Key))
+	}
+
+	return w.Flush()
+}
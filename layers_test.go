@@ -0,0 +1,297 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func testEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+
+		return v, ok
+	}
+}
+
+func TestResolveConfig(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home")
+	project := filepath.Join(home, "src", "monorepo")
+	service := filepath.Join(project, "services", "api")
+
+	userConfig := filepath.Join(home, ".config", "ksw", "config.yaml")
+	projectConfig := filepath.Join(project, ".ksw.yaml")
+
+	writeTestFile(t, userConfig, `kubeconfig:
+  minify: true
+check:
+  enabled: true
+  timeout: 3s
+contexts:
+  "*":
+    on_enter: echo global
+`)
+	writeTestFile(t, projectConfig, `context: prod
+namespace: payments
+allowed_contexts: [prod, staging-*]
+`)
+
+	if err := os.MkdirAll(service, 0755); err != nil {
+		t.Fatalf("Failed to create service dir: %v", err)
+	}
+
+	origProjectAllowed := projectAllowed
+	defer func() { projectAllowed = origProjectAllowed }()
+
+	projectAllowed = func(string, []byte) bool { return true }
+
+	resolved, err := resolveConfig(home, service, testEnv(map[string]string{
+		"KSW_CHECK_ENABLED":  "false",
+		"KSW_KUBECONFIG":     "/tmp/session.yaml",
+		"KSW_SESSION_DIR":    "/run/ksw",
+		"KSW_UNRELATED_NAME": "x",
+	}))
+	if err != nil {
+		t.Fatalf("resolveConfig() error = %v", err)
+	}
+
+	cfg := resolved.Config
+
+	if !cfg.Kubeconfig.Minify || cfg.Check.Enabled || time.Duration(cfg.Check.Timeout) != 3*time.Second || cfg.Session.Dir != "/run/ksw" {
+		t.Errorf("resolveConfig() = %+v", cfg)
+	}
+
+	if cfg.Context != "prod" || cfg.Namespace != "payments" || len(cfg.Contexts) != 1 {
+		t.Errorf("resolveConfig() = %+v", cfg)
+	}
+
+	if want := []string{userConfig, projectConfig}; !reflect.DeepEqual(resolved.Files, want) {
+		t.Errorf("Files = %v, want %v", resolved.Files, want)
+	}
+
+	origins := map[string]string{
+		"kubeconfig.minify":         userConfig,
+		"check.timeout":             userConfig,
+		"check.enabled":             "$KSW_CHECK_ENABLED",
+		"session.dir":               "$KSW_SESSION_DIR",
+		"namespace":                 projectConfig,
+		"allowed_contexts":          projectConfig,
+		"credentials.prefetch_exec": originDefault,
+	}
+
+	for key, want := range origins {
+		if got := resolved.origin(key); got != want {
+			t.Errorf("origin(%s) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestResolveConfigInvalidLayers(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+
+	writeTestFile(t, filepath.Join(home, ".ksw.yaml"), "check:\n  timeout: 3s\n")
+	writeTestFile(t, filepath.Join(project, ".ksw.yaml"), "check:\n  timout: 10s\n")
+
+	resolved, err := resolveConfig(home, project, testEnv(map[string]string{"KSW_CHECK_STRICT": "maybe"}))
+	if err == nil {
+		t.Fatalf("resolveConfig() expected errors")
+	}
+
+	for _, want := range []string{"check.timout: unknown key", "$KSW_CHECK_STRICT: expected true or false"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("resolveConfig() error = %v, want containing %q", err, want)
+		}
+	}
+
+	if got := time.Duration(resolved.Config.Check.Timeout); got != 3*time.Second {
+		t.Errorf("valid layers should still apply, got timeout %v", got)
+	}
+}
+
+func TestResolveConfigProjectScope(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+
+	writeTestFile(t, filepath.Join(project, ".ksw.yaml"), `context: staging
+contexts:
+  staging:
+    on_enter: touch /tmp/pwned
+session:
+  dir: /tmp
+`)
+
+	resolved, err := resolveConfig(home, project, testEnv(nil))
+	if err == nil {
+		t.Fatalf("resolveConfig() expected errors")
+	}
+
+	for _, want := range []string{"contexts: not allowed in a project config", "session: not allowed in a project config"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("resolveConfig() error = %v, want containing %q", err, want)
+		}
+	}
+
+	if resolved.Config.Context != "" || len(resolved.Config.Contexts) != 0 || resolved.Config.Session.Dir != "" {
+		t.Errorf("a project config with disallowed keys must not be applied, got %+v", resolved.Config)
+	}
+}
+
+func TestResolveConfigProjectNotAllowed(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+	projectConfig := filepath.Join(project, ".ksw.yaml")
+
+	writeTestFile(t, projectConfig, "allowed_contexts: [dev]\n")
+
+	origProjectAllowed := projectAllowed
+	defer func() { projectAllowed = origProjectAllowed }()
+
+	allowed := false
+	projectAllowed = func(path string, content []byte) bool { return allowed && path == projectConfig }
+
+	resolved, err := resolveConfig(home, project, testEnv(nil))
+	if err == nil || !strings.Contains(err.Error(), "not allowed, run 'ksw allow'") {
+		t.Errorf("resolveConfig() error = %v, want the project reported as not allowed", err)
+	}
+
+	if len(resolved.Config.AllowedContexts) != 0 || len(resolved.Files) != 0 {
+		t.Errorf("an unallowed project config must not be applied, got %+v", resolved)
+	}
+
+	allowed = true
+
+	resolved, err = resolveConfig(home, project, testEnv(nil))
+	if err != nil {
+		t.Fatalf("resolveConfig() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(resolved.Config.AllowedContexts, []string{"dev"}) {
+		t.Errorf("resolveConfig() allowed_contexts = %v, want [dev]", resolved.Config.AllowedContexts)
+	}
+}
+
+func TestFindProjectConfig(t *testing.T) {
+	home := t.TempDir()
+	userConfig := filepath.Join(home, ".ksw.yaml")
+	writeTestFile(t, userConfig, "")
+
+	dir := filepath.Join(home, "a", "b")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	if got := findProjectConfig(dir, []string{userConfig}); got != "" && strings.HasPrefix(got, home) {
+		t.Errorf("findProjectConfig() = %q, the user config must not be read as a project config", got)
+	}
+
+	projectConfig := filepath.Join(home, "a", ".ksw.yaml")
+	writeTestFile(t, projectConfig, "")
+
+	if got := findProjectConfig(dir, []string{userConfig}); got != projectConfig {
+		t.Errorf("findProjectConfig() = %q, want %q", got, projectConfig)
+	}
+}
+
+func TestResolveConfigInactiveUserConfig(t *testing.T) {
+	home := t.TempDir()
+	userConfig := filepath.Join(home, ".config", "ksw", "config.yaml")
+	dir := filepath.Join(home, "src", "app")
+
+	writeTestFile(t, userConfig, "check:\n  timeout: 3s\n")
+	// A legacy user config that is shadowed by the one above is still not a project config
+	writeTestFile(t, filepath.Join(home, ".ksw.yaml"), "contexts:\n  prod:\n    protected: true\n")
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "another candidate in use"},
+		{name: "KSW_CONFIG set", env: map[string]string{"KSW_CONFIG": userConfig}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := resolveConfig(home, dir, testEnv(tt.env))
+			if err != nil {
+				t.Fatalf("resolveConfig() error = %v", err)
+			}
+
+			if want := []string{userConfig}; !reflect.DeepEqual(resolved.Files, want) {
+				t.Errorf("Files = %v, want %v", resolved.Files, want)
+			}
+
+			if got := findProjectConfig(dir, userConfigPaths(home, testEnv(tt.env))); got != "" {
+				t.Errorf("findProjectConfig() = %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestConfigEnvVar(t *testing.T) {
+	if got := configEnvVar("kubeconfig.merge_on_exit.enabled"); got != "KSW_KUBECONFIG_MERGE_ON_EXIT_ENABLED" {
+		t.Errorf("configEnvVar() = %q", got)
+	}
+}
+
+func TestConfigFilePath(t *testing.T) {
+	home := t.TempDir()
+	xdg := t.TempDir()
+
+	legacy := filepath.Join(home, ".ksw.yaml")
+	writeTestFile(t, legacy, "")
+
+	xdgConfig := filepath.Join(xdg, "ksw", "config.yaml")
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		xdgFile bool
+		want    string
+	}{
+		{name: "legacy fallback", want: legacy},
+		{name: "XDG_CONFIG_HOME", env: map[string]string{"XDG_CONFIG_HOME": xdg}, xdgFile: true, want: xdgConfig},
+		{name: "XDG_CONFIG_HOME without file", env: map[string]string{"XDG_CONFIG_HOME": xdg}, want: legacy},
+		{name: "relative XDG_CONFIG_HOME is ignored", env: map[string]string{"XDG_CONFIG_HOME": "relative"}, want: legacy},
+		{
+			name:    "KSW_CONFIG wins even when missing",
+			env:     map[string]string{"KSW_CONFIG": "/missing/ksw.yaml", "XDG_CONFIG_HOME": xdg},
+			xdgFile: true,
+			want:    "/missing/ksw.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.RemoveAll(filepath.Join(xdg, "ksw"))
+
+			if tt.xdgFile {
+				writeTestFile(t, xdgConfig, "")
+			}
+
+			if got := configFilePath(home, testEnv(tt.env)); got != tt.want {
+				t.Errorf("configFilePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
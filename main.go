@@ -2,33 +2,265 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/ghodss/yaml"
 	"github.com/riywo/loginshell"
 	"github.com/urfave/cli/v2"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
 )
 
 func main() {
 	app := &cli.App{
-		Name:            "ksw",
-		Usage:           "kubeconfig switcher",
-		Description:     "start a new shell with specified kube context",
-		Action:          mainAction,
-		ArgsUsage:       "[context-query]",
-		HideHelpCommand: true,
-		Version:         Version,
-		HideVersion:     false,
+		Name:                 "ksw",
+		Usage:                "kubeconfig switcher",
+		Description:          "start a new shell with specified kube context",
+		Action:               mainAction,
+		Before:               globalFlagsBefore,
+		EnableBashCompletion: true,
+		BashComplete:         completeMain,
+		ArgsUsage:            "[context-query]",
+		HideHelpCommand:      true,
+		Version:              Version,
+		HideVersion:          false,
+		Commands: []*cli.Command{
+			{
+				Name:         "init",
+				Usage:        "print shell integration for bash or zsh",
+				ArgsUsage:    "<shell>",
+				Action:       initAction,
+				BashComplete: completeWords("bash", "zsh"),
+				Description: "add eval \"$(ksw init zsh)\" to your shell rc file so that context " +
+					"environment variables are applied when switching contexts inside a session",
+			},
+			{
+				Name:         "completion",
+				Usage:        "print shell completion for bash, zsh or fish",
+				ArgsUsage:    "<shell>",
+				Action:       completionAction,
+				BashComplete: completeWords("bash", "zsh", "fish"),
+				Description: "add eval \"$(ksw completion zsh)\" to your shell rc file, or for fish " +
+					"ksw completion fish | source, to complete subcommands, flags, tags and context names. " +
+					"Context names are cached until the kubeconfig changes",
+			},
+			{
+				Name:      "import",
+				Usage:     "import contexts, clusters and users from another kubeconfig file",
+				ArgsUsage: "<file>",
+				Action:    importAction,
+				Description: "shows the entries of <file> that are new or differ from the original kubeconfig " +
+					"and writes the selected ones back to it",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "prefix",
+						Usage: "prefix every imported context, cluster and user name",
+					},
+					&cli.BoolFlag{
+						Name:  "rename-conflicts",
+						Usage: "import entries whose name is taken by a different entry under a new name instead of overwriting it",
+					},
+				},
+			},
+			{
+				Name:         "export",
+				Usage:        "write a standalone kubeconfig for one or more contexts",
+				ArgsUsage:    "[context...]",
+				Action:       exportAction,
+				BashComplete: completeContextArgs(0),
+				Description: "the exported kubeconfig contains the given contexts and the clusters and users they " +
+					"reference, with the first context as the current context. Without contexts they are selected " +
+					"in the finder, several of them when finder.multi_select is enabled",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "write to `FILE` instead of stdout",
+					},
+					&cli.BoolFlag{
+						Name:  "flatten",
+						Usage: "inline referenced certificate and key files as *-data fields",
+					},
+					&cli.StringFlag{
+						Name:  "exec",
+						Usage: "exec plugin handling: keep, strip-env (drop plugin env vars) or token (replace with the credential it returns)",
+						Value: exportExecKeep,
+					},
+				},
+			},
+			{
+				Name:   "prune",
+				Usage:  "remove contexts from the original kubeconfig interactively",
+				Action: pruneAction,
+				Description: "clusters and users that are no longer referenced by any remaining context are removed " +
+					"as well, and the previous kubeconfig is kept next to it with a .ksw.bak suffix",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "unreachable",
+						Usage: "only offer contexts whose API server cannot be reached",
+					},
+					&cli.IntFlag{
+						Name:  "unused-days",
+						Usage: "only offer contexts not entered with ksw in the last `N` days",
+					},
+				},
+			},
+			{
+				Name:         "rename",
+				Usage:        "rename a context in the original kubeconfig",
+				ArgsUsage:    "<old> <new>",
+				Action:       renameAction,
+				BashComplete: completeContextArgs(1),
+			},
+			{
+				Name:         "edit",
+				Usage:        "edit a context with its cluster and user in $EDITOR",
+				ArgsUsage:    "<context>",
+				Action:       editAction,
+				BashComplete: completeContextArgs(1),
+				Description: "the edited document is validated before it is applied to the original kubeconfig, " +
+					"and invalid documents can be reopened in the editor",
+			},
+			{
+				Name:  "config",
+				Usage: "manage the ksw config file",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "view",
+						Usage:  "print the effective configuration",
+						Action: configViewAction,
+						Description: "settings are read from --config, $KSW_CONFIG or $XDG_CONFIG_HOME/ksw/config.yaml " +
+							"(or ~/.ksw.yaml), overridden by the nearest .ksw.yaml in the current directory or its parents, " +
+							"and by KSW_* environment variables",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "resolved",
+								Usage: "list every setting with the file or environment variable it came from",
+							},
+						},
+					},
+					{
+						Name:      "get",
+						Usage:     "print the effective value of a setting, or of every setting in a section",
+						ArgsUsage: "<key>",
+						Action:    configGetAction,
+					},
+					{
+						Name:      "set",
+						Usage:     "change a setting in the config file",
+						ArgsUsage: "<key> <value>",
+						Action:    configSetAction,
+						Description: "keys are dotted paths such as kubeconfig.merge_on_exit.enabled or contexts.prod-*.on_enter, " +
+							"and values are checked against the type of the setting. The config file is created when missing " +
+							"and its comments are kept",
+						Flags: []cli.Flag{configFileFlag},
+					},
+					{
+						Name:      "unset",
+						Usage:     "remove a setting from the config file",
+						ArgsUsage: "<key>",
+						Action:    configUnsetAction,
+						Flags:     []cli.Flag{configFileFlag},
+					},
+					{
+						Name:      "validate",
+						Usage:     "check the config file for unknown keys and invalid values",
+						ArgsUsage: "[file]",
+						Action:    configValidateAction,
+					},
+				},
+			},
+			{
+				Name:      "allow",
+				Usage:     "allow the project .ksw.yaml to switch context automatically",
+				ArgsUsage: "[dir]",
+				Action:    allowAction(true),
+				Description: "the nearest .ksw.yaml in dir or its parents is trusted with its current content, " +
+					"and has to be allowed again after it changes",
+			},
+			{
+				Name:      "deny",
+				Usage:     "stop the project .ksw.yaml from switching context automatically",
+				ArgsUsage: "[dir]",
+				Action:    allowAction(false),
+			},
+			{
+				Name:   "hook",
+				Usage:  "switch to the context of the project .ksw.yaml, run by the shell integration",
+				Hidden: true,
+				Action: hookAction,
+			},
+			{
+				Name:   "doctor",
+				Usage:  "check kubeconfig sources for problems",
+				Action: doctorAction,
+				Description: "exits with 1 when errors are found, 2 when only warnings are found, " +
+					"and 0 when the kubeconfig sources look healthy",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "output format: text or json",
+						Value:   "text",
+					},
+				},
+			},
+		},
 		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "config",
+				Usage:   "ksw config file to use instead of ~/.config/ksw/config.yaml",
+				EnvVars: []string{"KSW_CONFIG"},
+			},
+			&cli.StringFlag{
+				Name:  "kubeconfig",
+				Usage: "original kubeconfig to use instead of $KUBECONFIG or ~/.kube/config",
+			},
+			&cli.BoolFlag{
+				Name:  "non-interactive",
+				Usage: "never open the finder; ambiguous queries fail with the matching contexts and exit code 2",
+			},
 			&cli.BoolFlag{
 				Name:    "list",
 				Aliases: []string{"l"},
 				Usage:   "list available contexts without starting a shell",
 			},
+			&cli.StringSliceFlag{
+				Name:  "tag",
+				Usage: "only offer or list contexts tagged `KEY=VALUE` (or with any tag of VALUE), can be repeated",
+			},
+			&cli.StringFlag{
+				Name:  "group-by",
+				Usage: "with --list, group contexts by the value of tag `KEY`",
+			},
+			&cli.BoolFlag{
+				Name:  "probe",
+				Usage: "with --list, probe every context's API server for reachability, version and auth",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "output format for --list: name or wide",
+				Value:   "name",
+			},
 			&cli.BoolFlag{
 				Name:    "env",
 				Aliases: []string{"e"},
 				Usage:   "print ksw environment variables",
 			},
+			&cli.BoolFlag{
+				Name:  "check",
+				Usage: "check that the cluster is reachable before entering the context",
+			},
+			&cli.BoolFlag{
+				Name: "show-secrets",
+				Usage: "show tokens, passwords and keys instead of redacting them in the finder preview, " +
+					"in context env values shown by config view and get, and in kubeconfigs exported to a terminal",
+			},
 		},
 	}
 
@@ -42,10 +274,42 @@ func main() {
 	}
 }
 
+// configFileFlag selects the file changed by 'ksw config set' and 'ksw config unset'.
+var configFileFlag = &cli.StringFlag{
+	Name:  "file",
+	Usage: "change `FILE`, such as a project .ksw.yaml, instead of the user config file",
+}
+
+// globalFlagsBefore applies --config, --kubeconfig and --non-interactive. The config file is exported as KSW_CONFIG
+// so that a session started with it keeps using it when switching contexts.
+func globalFlagsBefore(c *cli.Context) error {
+	if path := c.String("config"); path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+
+		_ = os.Setenv("KSW_CONFIG", abs)
+	}
+
+	nonInteractive = c.Bool("non-interactive")
+
+	if list := c.String("kubeconfig"); list != "" {
+		abs, err := absPathList(list)
+		if err != nil {
+			return err
+		}
+
+		kubeconfigFlag = abs
+	}
+
+	return nil
+}
+
 func mainAction(c *cli.Context) error {
 	// Handle --list flag
 	if c.Bool("list") {
-		return listContextsAction()
+		return listContextsAction(c.String("output"), c.Bool("probe"), c.StringSlice("tag"), c.String("group-by"))
 	}
 
 	// Handle --env flag
@@ -60,14 +324,18 @@ func mainAction(c *cli.Context) error {
 	}
 
 	// Show fuzzy finder with initial query
-	contextName, err := findContext(query)
+	contextName, err := findContext(query, c.StringSlice("tag"), c.Bool("show-secrets"))
 	if err != nil {
 		return err
 	}
 
+	opts := sessionOptions{
+		Check: c.Bool("check"),
+	}
+
 	// If already in a ksw session, switch context in-place instead of nesting
 	if os.Getenv("KSW_KUBECONFIG_ORIGINAL") != "" {
-		return switchContext(contextName)
+		return switchContext(contextName, opts)
 	}
 
 	// Otherwise, start a new shell with the selected context
@@ -76,24 +344,134 @@ func mainAction(c *cli.Context) error {
 		return err
 	}
 
-	return startShell(shell, contextName)
+	return startShell(shell, contextName, opts)
 }
 
-func listContextsAction() error {
+// listContextsAction lists the allowed contexts matching the tag filters, grouped by the value of
+// the groupBy tag when it is set.
+func listContextsAction(output string, probe bool, tags []string, groupBy string) error {
 	kubeconfigPath := getOriginalKubeconfigPath()
 
-	contexts, err := listContexts(kubeconfigPath)
+	if output != "" && output != "name" && output != "wide" {
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+
+	kubeconfigBytes, _, err := readKubeconfigBytes(kubeconfigPath)
 	if err != nil {
 		return err
 	}
 
-	for _, ctx := range contexts {
-		fmt.Println(ctx)
+	var config apiv1.Config
+	if err := yaml.Unmarshal(kubeconfigBytes, &config); err != nil {
+		return err
+	}
+
+	cfg := loadConfig()
+
+	filters := make([]tagFilter, len(tags))
+	for i, tag := range tags {
+		filters[i] = parseTagFilter(tag)
+	}
+
+	contexts := make([]string, 0, len(config.Contexts))
+	for _, ctx := range config.Contexts {
+		contexts = append(contexts, ctx.Name)
+	}
+
+	contexts = filterContextsByTags(cfg, cfg.allowedContexts(contexts), filters)
+
+	groups := []contextGroup{{Contexts: contexts}}
+	if groupBy != "" {
+		groups = groupContextsByTag(cfg, contexts, groupBy)
+	}
+
+	var statuses map[string]ClusterStatus
+
+	if probe {
+		statuses = probeContexts(kubeconfigBytes, kubeconfigPath, contexts, time.Duration(cfg.Check.Timeout))
+	}
+
+	byName := contextsMap(config.Contexts)
+
+	for i, group := range groups {
+		if group.Name != "" {
+			if i > 0 {
+				fmt.Println()
+			}
+
+			fmt.Printf("%s:\n", group.Name)
+		}
+
+		if (output == "" || output == "name") && !probe {
+			for _, ctx := range group.Contexts {
+				if group.Name != "" {
+					fmt.Print("  ")
+				}
+
+				fmt.Println(ctx)
+			}
+
+			continue
+		}
+
+		subset := config
+		subset.Contexts = make([]apiv1.NamedContext, len(group.Contexts))
+
+		for j, name := range group.Contexts {
+			subset.Contexts[j] = apiv1.NamedContext{Name: name, Context: byName[name]}
+		}
+
+		printContextsTable(os.Stdout, subset, time.Now(), output == "wide", statuses)
 	}
 
 	return nil
 }
 
+// printContextsTable writes a table of contexts. Wide output adds the cluster, user, namespace
+// and credential expiry, and probe results add reachability, version, auth result and latency.
+func printContextsTable(out io.Writer, config apiv1.Config, now time.Time, wide bool, statuses map[string]ClusterStatus) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	header := []string{"NAME"}
+	if wide {
+		header = append(header, "CLUSTER", "USER", "NAMESPACE", "EXPIRES")
+	}
+
+	if statuses != nil {
+		header = append(header, "STATUS", "VERSION", "AUTH", "LATENCY")
+	}
+
+	_, _ = fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, ctx := range config.Contexts {
+		row := []string{ctx.Name}
+
+		if wide {
+			expires := ""
+			if expiry, ok := contextCredentialExpiry(config, ctx.Name); ok {
+				expires = formatExpiry(expiry, now)
+			}
+
+			row = append(row, ctx.Context.Cluster, ctx.Context.AuthInfo, ctx.Context.Namespace, expires)
+		}
+
+		if statuses != nil {
+			status := statuses[ctx.Name]
+
+			latency := ""
+			if status.Reachable {
+				latency = status.Latency.Round(time.Millisecond).String()
+			}
+
+			row = append(row, status.State(), status.Version, status.Auth, latency)
+		}
+
+		_, _ = fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	_ = w.Flush()
+}
+
 func envAction() error {
 	envVars := []string{
 		"KSW_ACTIVE",
@@ -6,6 +6,9 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
+
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
 )
 
 func TestEnvAction(t *testing.T) {
@@ -143,3 +146,73 @@ func TestEnvActionNoError(t *testing.T) {
 		t.Errorf("envAction() returned unexpected error: %v", err)
 	}
 }
+
+func TestPrintContextsTable(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	config := apiv1.Config{
+		Contexts: []apiv1.NamedContext{
+			{Name: "prod", Context: apiv1.Context{Cluster: "prod-cluster", AuthInfo: "prod-user", Namespace: "web"}},
+			{Name: "dev", Context: apiv1.Context{Cluster: "dev-cluster", AuthInfo: "dev-user"}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "prod-user", AuthInfo: apiv1.AuthInfo{ClientCertificateData: newTestCertificate(t, now.Add(-time.Hour))}},
+			{Name: "dev-user", AuthInfo: apiv1.AuthInfo{Token: "opaque"}},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	printContextsTable(&buf, config, now, true, nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("printContextsTable() printed %d lines, want 3\nGot output:\n%s", len(lines), buf.String())
+	}
+
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "NAME CLUSTER USER NAMESPACE EXPIRES" {
+		t.Errorf("printContextsTable() header = %q", lines[0])
+	}
+
+	if !strings.HasPrefix(lines[1], "prod") || !strings.Contains(lines[1], "web") || !strings.Contains(lines[1], "(expired)") {
+		t.Errorf("printContextsTable() prod row = %q", lines[1])
+	}
+
+	if fields := strings.Fields(lines[2]); len(fields) != 3 {
+		t.Errorf("printContextsTable() dev row = %q, want name, cluster and user only", lines[2])
+	}
+}
+
+func TestPrintContextsTableProbe(t *testing.T) {
+	config := apiv1.Config{
+		Contexts: []apiv1.NamedContext{
+			{Name: "prod", Context: apiv1.Context{Cluster: "prod-cluster", AuthInfo: "prod-user"}},
+			{Name: "stale", Context: apiv1.Context{Cluster: "stale-cluster", AuthInfo: "stale-user"}},
+		},
+	}
+
+	statuses := map[string]ClusterStatus{
+		"prod": {Reachable: true, Ready: true, Version: "v1.31.2", Auth: "OK", Latency: 42 * time.Millisecond},
+	}
+
+	var buf bytes.Buffer
+
+	printContextsTable(&buf, config, time.Now(), false, statuses)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("printContextsTable() printed %d lines, want 3\nGot output:\n%s", len(lines), buf.String())
+	}
+
+	if got := strings.Join(strings.Fields(lines[0]), " "); got != "NAME STATUS VERSION AUTH LATENCY" {
+		t.Errorf("printContextsTable() header = %q", got)
+	}
+
+	if got := strings.Join(strings.Fields(lines[1]), " "); got != "prod Ready v1.31.2 OK 42ms" {
+		t.Errorf("printContextsTable() prod row = %q", got)
+	}
+
+	if got := strings.Join(strings.Fields(lines[2]), " "); got != "stale Unreachable" {
+		t.Errorf("printContextsTable() stale row = %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"reflect"
 	"slices"
@@ -10,26 +11,35 @@ import (
 	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
 )
 
-// KubeconfigDiff represents the added, modified, and deleted entities between configs.
+// KubeconfigDiff represents the added, modified, renamed, and deleted entities between configs.
 type KubeconfigDiff struct {
 	ContextsAdded    []apiv1.NamedContext
 	ContextsModified []apiv1.NamedContext
+	ContextsRenamed  []KubeconfigRename
 	ContextsDeleted  []string
 
 	ClustersAdded    []apiv1.NamedCluster
 	ClustersModified []apiv1.NamedCluster
+	ClustersRenamed  []KubeconfigRename
 	ClustersDeleted  []string
 
 	UsersAdded    []apiv1.NamedAuthInfo
 	UsersModified []apiv1.NamedAuthInfo
+	UsersRenamed  []KubeconfigRename
 	UsersDeleted  []string
 }
 
+// KubeconfigRename records an entity whose name changed while its body stayed the same.
+type KubeconfigRename struct {
+	From string
+	To   string
+}
+
 // HasChanges returns true if there are any differences.
 func (d KubeconfigDiff) HasChanges() bool {
-	return len(d.ContextsAdded) > 0 || len(d.ContextsModified) > 0 || len(d.ContextsDeleted) > 0 ||
-		len(d.ClustersAdded) > 0 || len(d.ClustersModified) > 0 || len(d.ClustersDeleted) > 0 ||
-		len(d.UsersAdded) > 0 || len(d.UsersModified) > 0 || len(d.UsersDeleted) > 0
+	return len(d.ContextsAdded) > 0 || len(d.ContextsModified) > 0 || len(d.ContextsRenamed) > 0 || len(d.ContextsDeleted) > 0 ||
+		len(d.ClustersAdded) > 0 || len(d.ClustersModified) > 0 || len(d.ClustersRenamed) > 0 || len(d.ClustersDeleted) > 0 ||
+		len(d.UsersAdded) > 0 || len(d.UsersModified) > 0 || len(d.UsersRenamed) > 0 || len(d.UsersDeleted) > 0
 }
 
 func contextsMap(contexts []apiv1.NamedContext) map[string]apiv1.Context {
@@ -62,7 +72,64 @@ func usersMap(authInfos []apiv1.NamedAuthInfo) map[string]apiv1.AuthInfo {
 	return m
 }
 
+// renameTarget returns the new name for name if it appears in renames, or name itself otherwise.
+func renameTarget(renames []KubeconfigRename, name string) string {
+	for _, r := range renames {
+		if r.From == name {
+			return r.To
+		}
+	}
+
+	return name
+}
+
+// renamedFrom reports whether name is the old name of any rename.
+func renamedFrom(renames []KubeconfigRename, name string) bool {
+	return slices.ContainsFunc(renames, func(r KubeconfigRename) bool { return r.From == name })
+}
+
+// renamedTo reports whether name is the new name of any rename.
+func renamedTo(renames []KubeconfigRename, name string) bool {
+	return slices.ContainsFunc(renames, func(r KubeconfigRename) bool { return r.To == name })
+}
+
+// detectRenames pairs added and deleted entities that have identical bodies.
+// Candidates are matched in alphabetical order so the result is deterministic
+// when several deleted entities share the same body.
+func detectRenames[V any](added, deleted map[string]V) []KubeconfigRename {
+	var renames []KubeconfigRename
+
+	used := make(map[string]bool)
+
+	for _, to := range slices.Sorted(maps.Keys(added)) {
+		for _, from := range slices.Sorted(maps.Keys(deleted)) {
+			if used[from] || !reflect.DeepEqual(added[to], deleted[from]) {
+				continue
+			}
+
+			used[from] = true
+			renames = append(renames, KubeconfigRename{From: from, To: to})
+
+			break
+		}
+	}
+
+	return renames
+}
+
+// renameContextRefs rewrites the cluster and user references of a context according to the given renames.
+func renameContextRefs(ctx apiv1.Context, clusterRenames, userRenames []KubeconfigRename) apiv1.Context {
+	ctx.Cluster = renameTarget(clusterRenames, ctx.Cluster)
+	ctx.AuthInfo = renameTarget(userRenames, ctx.AuthInfo)
+
+	return ctx
+}
+
 // computeKubeconfigDiff calculates the differences between original and temporary configs.
+//
+// Entities that disappear under one name and reappear with an identical body under another
+// are reported as renames. Since deletions are not tracked for minified sessions, renames are
+// only detected when minified is false.
 func computeKubeconfigDiff(orig, temp apiv1.Config, minified bool) KubeconfigDiff {
 	var diff KubeconfigDiff
 
@@ -75,66 +142,148 @@ func computeKubeconfigDiff(orig, temp apiv1.Config, minified bool) KubeconfigDif
 	origUsers := usersMap(orig.AuthInfos)
 	tempUsers := usersMap(temp.AuthInfos)
 
-	// Compare contexts
-	for name, tempCtx := range tempCtxs {
-		if origCtx, exists := origCtxs[name]; !exists {
-			diff.ContextsAdded = append(diff.ContextsAdded, apiv1.NamedContext{Name: name, Context: tempCtx})
-		} else if !reflect.DeepEqual(origCtx, tempCtx) {
-			diff.ContextsModified = append(diff.ContextsModified, apiv1.NamedContext{Name: name, Context: tempCtx})
-		}
-	}
-
-	if !minified {
-		for name := range origCtxs {
-			if _, exists := tempCtxs[name]; !exists {
-				diff.ContextsDeleted = append(diff.ContextsDeleted, name)
-			}
-		}
-	}
-
 	// Compare clusters
+	addedClusters := make(map[string]apiv1.Cluster)
+	deletedClusters := make(map[string]apiv1.Cluster)
+
 	for name, tempCluster := range tempClusters {
 		if origCluster, exists := origClusters[name]; !exists {
-			diff.ClustersAdded = append(diff.ClustersAdded, apiv1.NamedCluster{Name: name, Cluster: tempCluster})
+			addedClusters[name] = tempCluster
 		} else if !reflect.DeepEqual(origCluster, tempCluster) {
 			diff.ClustersModified = append(diff.ClustersModified, apiv1.NamedCluster{Name: name, Cluster: tempCluster})
 		}
 	}
 
 	if !minified {
-		for name := range origClusters {
+		for name, origCluster := range origClusters {
 			if _, exists := tempClusters[name]; !exists {
-				diff.ClustersDeleted = append(diff.ClustersDeleted, name)
+				deletedClusters[name] = origCluster
 			}
 		}
+
+		diff.ClustersRenamed = detectRenames(addedClusters, deletedClusters)
+	}
+
+	for name, cluster := range addedClusters {
+		if !renamedTo(diff.ClustersRenamed, name) {
+			diff.ClustersAdded = append(diff.ClustersAdded, apiv1.NamedCluster{Name: name, Cluster: cluster})
+		}
+	}
+
+	for name := range deletedClusters {
+		if !renamedFrom(diff.ClustersRenamed, name) {
+			diff.ClustersDeleted = append(diff.ClustersDeleted, name)
+		}
 	}
 
 	// Compare users
+	addedUsers := make(map[string]apiv1.AuthInfo)
+	deletedUsers := make(map[string]apiv1.AuthInfo)
+
 	for name, tempUser := range tempUsers {
 		if origUser, exists := origUsers[name]; !exists {
-			diff.UsersAdded = append(diff.UsersAdded, apiv1.NamedAuthInfo{Name: name, AuthInfo: tempUser})
+			addedUsers[name] = tempUser
 		} else if !reflect.DeepEqual(origUser, tempUser) {
 			diff.UsersModified = append(diff.UsersModified, apiv1.NamedAuthInfo{Name: name, AuthInfo: tempUser})
 		}
 	}
 
 	if !minified {
-		for name := range origUsers {
+		for name, origUser := range origUsers {
 			if _, exists := tempUsers[name]; !exists {
-				diff.UsersDeleted = append(diff.UsersDeleted, name)
+				deletedUsers[name] = origUser
 			}
 		}
+
+		diff.UsersRenamed = detectRenames(addedUsers, deletedUsers)
+	}
+
+	for name, user := range addedUsers {
+		if !renamedTo(diff.UsersRenamed, name) {
+			diff.UsersAdded = append(diff.UsersAdded, apiv1.NamedAuthInfo{Name: name, AuthInfo: user})
+		}
+	}
+
+	for name := range deletedUsers {
+		if !renamedFrom(diff.UsersRenamed, name) {
+			diff.UsersDeleted = append(diff.UsersDeleted, name)
+		}
+	}
+
+	// Compare contexts, treating references to renamed clusters and users as unchanged
+	addedCtxs := make(map[string]apiv1.Context)
+	deletedCtxs := make(map[string]apiv1.Context)
+
+	for name, tempCtx := range tempCtxs {
+		if origCtx, exists := origCtxs[name]; !exists {
+			addedCtxs[name] = tempCtx
+		} else if !reflect.DeepEqual(renameContextRefs(origCtx, diff.ClustersRenamed, diff.UsersRenamed), tempCtx) {
+			diff.ContextsModified = append(diff.ContextsModified, apiv1.NamedContext{Name: name, Context: tempCtx})
+		}
+	}
+
+	if !minified {
+		for name, origCtx := range origCtxs {
+			if _, exists := tempCtxs[name]; !exists {
+				deletedCtxs[name] = renameContextRefs(origCtx, diff.ClustersRenamed, diff.UsersRenamed)
+			}
+		}
+
+		diff.ContextsRenamed = detectRenames(addedCtxs, deletedCtxs)
+	}
+
+	for name, ctx := range addedCtxs {
+		if !renamedTo(diff.ContextsRenamed, name) {
+			diff.ContextsAdded = append(diff.ContextsAdded, apiv1.NamedContext{Name: name, Context: ctx})
+		}
+	}
+
+	for name := range deletedCtxs {
+		if !renamedFrom(diff.ContextsRenamed, name) {
+			diff.ContextsDeleted = append(diff.ContextsDeleted, name)
+		}
 	}
 
 	return diff
 }
 
 // applyDiff merges the selected differences back into a config.
+//
+// Renames are applied first so that references held by other contexts, and the
+// current context, follow the new names before additions and modifications land.
 func applyDiff(orig apiv1.Config, diff KubeconfigDiff) apiv1.Config {
 	ctxMap := contextsMap(orig.Contexts)
 	clusterMap := clustersMap(orig.Clusters)
 	userMap := usersMap(orig.AuthInfos)
 
+	// Apply renames
+	for _, r := range diff.ClustersRenamed {
+		if x, exists := clusterMap[r.From]; exists {
+			delete(clusterMap, r.From)
+			clusterMap[r.To] = x
+		}
+	}
+
+	for _, r := range diff.UsersRenamed {
+		if x, exists := userMap[r.From]; exists {
+			delete(userMap, r.From)
+			userMap[r.To] = x
+		}
+	}
+
+	for name, x := range ctxMap {
+		ctxMap[name] = renameContextRefs(x, diff.ClustersRenamed, diff.UsersRenamed)
+	}
+
+	for _, r := range diff.ContextsRenamed {
+		if x, exists := ctxMap[r.From]; exists {
+			delete(ctxMap, r.From)
+			ctxMap[r.To] = x
+		}
+	}
+
+	orig.CurrentContext = renameTarget(diff.ContextsRenamed, orig.CurrentContext)
+
 	// Apply contexts
 	for _, x := range diff.ContextsAdded {
 		ctxMap[x.Name] = x.Context
@@ -229,7 +378,7 @@ func applyDiff(orig apiv1.Config, diff KubeconfigDiff) apiv1.Config {
 
 // mergeOnExit loads both configs, identifies changes, shows an interactive prompt, and applies them.
 func mergeOnExit(originalPath, tempPath string, minified bool) error {
-	origBytes, err := os.ReadFile(originalPath)
+	origBytes, _, err := readKubeconfigBytes(originalPath)
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to read original kubeconfig: %w", err)
 	}
@@ -269,30 +418,85 @@ func mergeOnExit(originalPath, tempPath string, minified bool) error {
 		return nil
 	}
 
-	latestOrigBytes, err := os.ReadFile(originalPath)
+	applied, err := applyChangesToOriginal(originalPath, selectedDiff)
+	if err != nil || !applied {
+		return err
+	}
+
+	fmt.Println("Selected changes successfully applied back to original kubeconfig.")
+
+	return nil
+}
+
+// applyChangesToOriginal applies selected changes to the latest version of the original kubeconfig
+// and writes it back, re-encrypting it if needed. When the changes would leave dangling references
+// the user is asked to confirm first. It reports whether the changes were written.
+func applyChangesToOriginal(originalPath string, selectedDiff KubeconfigDiff) (bool, error) {
+	latestOrigBytes, enc, err := readKubeconfigBytes(originalPath)
 
 	var latestOrigConfig apiv1.Config
 
 	if err == nil {
 		if err := yaml.Unmarshal(latestOrigBytes, &latestOrigConfig); err != nil {
-			return fmt.Errorf("failed to unmarshal latest original kubeconfig: %w", err)
+			return false, fmt.Errorf("failed to unmarshal latest original kubeconfig: %w", err)
 		}
 	} else if !os.IsNotExist(err) {
-		return fmt.Errorf("failed to read latest original kubeconfig: %w", err)
+		return false, fmt.Errorf("failed to read latest original kubeconfig: %w", err)
 	}
 
 	mergedConfig := applyDiff(latestOrigConfig, selectedDiff)
 
+	if dangling := newDanglingReferences(latestOrigConfig, mergedConfig); len(dangling) > 0 {
+		fmt.Println("\033[33mWarning:\033[0m the selected changes leave dangling references:")
+
+		for _, r := range dangling {
+			fmt.Printf("  - %s\n", r)
+		}
+
+		apply, err := confirm("Apply the selected changes anyway?")
+		if err != nil {
+			return false, fmt.Errorf("error confirming changes: %w", err)
+		}
+
+		if !apply {
+			fmt.Println("Merge discarded. No changes applied.")
+
+			return false, nil
+		}
+	}
+
 	mergedBytes, err := yaml.Marshal(mergedConfig)
 	if err != nil {
-		return fmt.Errorf("failed to marshal merged kubeconfig: %w", err)
+		return false, fmt.Errorf("failed to marshal merged kubeconfig: %w", err)
 	}
 
-	if err := os.WriteFile(originalPath, mergedBytes, 0600); err != nil {
-		return fmt.Errorf("failed to write original kubeconfig: %w", err)
+	if err := backupKubeconfig(originalPath); err != nil {
+		return false, fmt.Errorf("failed to back up original kubeconfig: %w", err)
 	}
 
-	fmt.Println("Selected changes successfully applied back to original kubeconfig.")
+	if err := writeKubeconfigBytes(originalPath, mergedBytes, enc); err != nil {
+		return false, fmt.Errorf("failed to write original kubeconfig: %w", err)
+	}
 
-	return nil
+	return true, nil
+}
+
+// backupPath returns where the previous version of a kubeconfig is kept before ksw rewrites it.
+func backupPath(path string) string {
+	return path + ".ksw.bak"
+}
+
+// backupKubeconfig copies the kubeconfig at path, as stored on disk, to its backup path.
+// Encrypted files stay encrypted. A missing file has nothing to back up.
+func backupKubeconfig(path string) error {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(backupPath(path), b, 0600)
 }
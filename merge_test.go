@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -120,6 +122,79 @@ func TestComputeKubeconfigDiff(t *testing.T) {
 		}
 	})
 
+	t.Run("renamed context", func(t *testing.T) {
+		temp := orig
+		temp.Contexts = []apiv1.NamedContext{
+			orig.Contexts[0],
+			{Name: "c2-renamed", Context: orig.Contexts[1].Context},
+		}
+
+		diff := computeKubeconfigDiff(orig, temp, false)
+
+		want := []KubeconfigRename{{From: "c2", To: "c2-renamed"}}
+		if !reflect.DeepEqual(diff.ContextsRenamed, want) {
+			t.Errorf("expected context rename %v, got %v", want, diff.ContextsRenamed)
+		}
+
+		if len(diff.ContextsAdded) != 0 || len(diff.ContextsDeleted) != 0 {
+			t.Errorf("expected no context additions or deletions, got added %v, deleted %v", diff.ContextsAdded, diff.ContextsDeleted)
+		}
+	})
+
+	t.Run("renamed cluster and user with updated references", func(t *testing.T) {
+		temp := orig
+		temp.Contexts = []apiv1.NamedContext{
+			orig.Contexts[0],
+			{Name: "c2", Context: apiv1.Context{Cluster: "cluster2-renamed", AuthInfo: "user2-renamed"}},
+		}
+		temp.Clusters = []apiv1.NamedCluster{
+			orig.Clusters[0],
+			{Name: "cluster2-renamed", Cluster: orig.Clusters[1].Cluster},
+		}
+		temp.AuthInfos = []apiv1.NamedAuthInfo{
+			orig.AuthInfos[0],
+			{Name: "user2-renamed", AuthInfo: orig.AuthInfos[1].AuthInfo},
+		}
+
+		diff := computeKubeconfigDiff(orig, temp, false)
+
+		wantClusters := []KubeconfigRename{{From: "cluster2", To: "cluster2-renamed"}}
+		if !reflect.DeepEqual(diff.ClustersRenamed, wantClusters) {
+			t.Errorf("expected cluster rename %v, got %v", wantClusters, diff.ClustersRenamed)
+		}
+
+		wantUsers := []KubeconfigRename{{From: "user2", To: "user2-renamed"}}
+		if !reflect.DeepEqual(diff.UsersRenamed, wantUsers) {
+			t.Errorf("expected user rename %v, got %v", wantUsers, diff.UsersRenamed)
+		}
+
+		if len(diff.ContextsModified) != 0 {
+			t.Errorf("expected reference updates to be covered by renames, got modified %v", diff.ContextsModified)
+		}
+
+		if len(diff.ClustersAdded) != 0 || len(diff.ClustersDeleted) != 0 || len(diff.UsersAdded) != 0 || len(diff.UsersDeleted) != 0 {
+			t.Errorf("expected no additions or deletions, got %+v", diff)
+		}
+	})
+
+	t.Run("rename with changed body is not detected", func(t *testing.T) {
+		temp := orig
+		temp.Clusters = []apiv1.NamedCluster{
+			orig.Clusters[0],
+			{Name: "cluster2-renamed", Cluster: apiv1.Cluster{Server: "https://other.example.com"}},
+		}
+
+		diff := computeKubeconfigDiff(orig, temp, false)
+
+		if len(diff.ClustersRenamed) != 0 {
+			t.Errorf("expected no cluster renames, got %v", diff.ClustersRenamed)
+		}
+
+		if len(diff.ClustersAdded) != 1 || len(diff.ClustersDeleted) != 1 {
+			t.Errorf("expected 1 cluster added and 1 deleted, got added %v, deleted %v", diff.ClustersAdded, diff.ClustersDeleted)
+		}
+	})
+
 	t.Run("deletions ignored when minified", func(t *testing.T) {
 		temp := orig
 		// Remove c2, cluster2, and user2 (mimicking minified state)
@@ -209,3 +284,85 @@ func TestApplyDiff(t *testing.T) {
 		t.Errorf("applyDiff Users = %v, want %v", got.AuthInfos, wantUsers)
 	}
 }
+
+func TestApplyDiffRenames(t *testing.T) {
+	orig := apiv1.Config{
+		CurrentContext: "c1",
+		Contexts: []apiv1.NamedContext{
+			{Name: "c1", Context: apiv1.Context{Cluster: "cluster1", AuthInfo: "user1"}},
+			{Name: "c2", Context: apiv1.Context{Cluster: "cluster1", AuthInfo: "user1"}},
+		},
+		Clusters: []apiv1.NamedCluster{
+			{Name: "cluster1", Cluster: apiv1.Cluster{Server: "https://c1.example.com"}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "user1", AuthInfo: apiv1.AuthInfo{Token: "token1"}},
+		},
+	}
+
+	diff := KubeconfigDiff{
+		ContextsRenamed: []KubeconfigRename{{From: "c1", To: "c1-new"}},
+		ClustersRenamed: []KubeconfigRename{{From: "cluster1", To: "cluster1-new"}},
+		UsersRenamed:    []KubeconfigRename{{From: "user1", To: "user1-new"}},
+	}
+
+	got := applyDiff(orig, diff)
+
+	wantContexts := []apiv1.NamedContext{
+		{Name: "c1-new", Context: apiv1.Context{Cluster: "cluster1-new", AuthInfo: "user1-new"}},
+		{Name: "c2", Context: apiv1.Context{Cluster: "cluster1-new", AuthInfo: "user1-new"}},
+	}
+
+	if !reflect.DeepEqual(got.Contexts, wantContexts) {
+		t.Errorf("applyDiff Contexts = %v, want %v", got.Contexts, wantContexts)
+	}
+
+	wantClusters := []apiv1.NamedCluster{
+		{Name: "cluster1-new", Cluster: apiv1.Cluster{Server: "https://c1.example.com"}},
+	}
+
+	if !reflect.DeepEqual(got.Clusters, wantClusters) {
+		t.Errorf("applyDiff Clusters = %v, want %v", got.Clusters, wantClusters)
+	}
+
+	wantUsers := []apiv1.NamedAuthInfo{
+		{Name: "user1-new", AuthInfo: apiv1.AuthInfo{Token: "token1"}},
+	}
+
+	if !reflect.DeepEqual(got.AuthInfos, wantUsers) {
+		t.Errorf("applyDiff Users = %v, want %v", got.AuthInfos, wantUsers)
+	}
+
+	if got.CurrentContext != "c1-new" {
+		t.Errorf("applyDiff CurrentContext = %v, want %v", got.CurrentContext, "c1-new")
+	}
+}
+
+func TestBackupKubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+
+	if err := backupKubeconfig(path); err != nil {
+		t.Fatalf("backupKubeconfig() error for missing file = %v", err)
+	}
+
+	if _, err := os.Stat(backupPath(path)); !os.IsNotExist(err) {
+		t.Errorf("expected no backup for a missing kubeconfig")
+	}
+
+	if err := os.WriteFile(path, []byte("original"), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+
+	if err := backupKubeconfig(path); err != nil {
+		t.Fatalf("backupKubeconfig() error = %v", err)
+	}
+
+	b, err := os.ReadFile(backupPath(path))
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+
+	if string(b) != "original" {
+		t.Errorf("backup = %q, want %q", b, "original")
+	}
+}
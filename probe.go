@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// probeWorkers bounds how many clusters are probed concurrently.
+const probeWorkers = 8
+
+// ClusterStatus is the result of probing the API server of a context.
+type ClusterStatus struct {
+	Reachable bool
+	Ready     bool
+	Version   string
+	// Auth is "OK", "401" or "403" depending on how the API server treated the credentials.
+	Auth    string
+	Latency time.Duration
+	Err     error
+}
+
+// State returns a short reachability label for listings.
+func (s ClusterStatus) State() string {
+	switch {
+	case !s.Reachable:
+		return "Unreachable"
+	case !s.Ready:
+		return "NotReady"
+	default:
+		return "Ready"
+	}
+}
+
+// String returns a one line summary of the status.
+func (s ClusterStatus) String() string {
+	if !s.Reachable {
+		return fmt.Sprintf("unreachable: %v", s.Err)
+	}
+
+	if s.Version == "" {
+		return fmt.Sprintf("reachable, credentials rejected (%s), %s", s.Auth, s.Latency.Round(time.Millisecond))
+	}
+
+	readiness := "ready"
+	if !s.Ready {
+		readiness = "not ready"
+	}
+
+	return fmt.Sprintf("Kubernetes %s, %s, %s", s.Version, readiness, s.Latency.Round(time.Millisecond))
+}
+
+// probeCluster queries /version, /readyz and /api of contextName in kubeconfig, or of its current
+// context when contextName is empty. Relative file references are resolved against source, the
+// kubeconfig the contexts come from. Exec plugins run without stdin, so probing never prompts.
+func probeCluster(ctx context.Context, kubeconfig []byte, source, contextName string, timeout time.Duration) ClusterStatus {
+	var status ClusterStatus
+
+	apiConfig, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		status.Err = err
+
+		return status
+	}
+
+	for _, cluster := range apiConfig.Clusters {
+		cluster.LocationOfOrigin = source
+	}
+
+	for _, user := range apiConfig.AuthInfos {
+		user.LocationOfOrigin = source
+
+		if user.Exec != nil {
+			user.Exec.InteractiveMode = clientcmdapi.NeverExecInteractiveMode
+		}
+	}
+
+	if source != "" {
+		if err := clientcmd.ResolveLocalPaths(apiConfig); err != nil {
+			status.Err = err
+
+			return status
+		}
+	}
+
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*apiConfig, contextName, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		status.Err = err
+
+		return status
+	}
+
+	restConfig.Timeout = timeout
+
+	client, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		status.Err = err
+
+		return status
+	}
+
+	start := time.Now()
+
+	info, err := client.ServerVersion()
+	if err != nil {
+		status.Err = err
+
+		// Clusters without anonymous access reject /version along with the credentials
+		if auth := authStatus(err); auth != "" {
+			status.Reachable = true
+			status.Latency = time.Since(start)
+			status.Auth = auth
+		}
+
+		return status
+	}
+
+	status.Reachable = true
+	status.Latency = time.Since(start)
+	status.Version = info.GitVersion
+
+	if _, err := client.RESTClient().Get().AbsPath("/readyz").DoRaw(ctx); err != nil {
+		status.Err = err
+	} else {
+		status.Ready = true
+	}
+
+	// /version is usually public, while discovery requires authenticated credentials
+	_, err = client.RESTClient().Get().AbsPath("/api").DoRaw(ctx)
+	if err == nil {
+		status.Auth = "OK"
+	} else {
+		status.Auth = authStatus(err)
+	}
+
+	return status
+}
+
+// authStatus returns "401" or "403" when err is the API server rejecting the credentials, or "" otherwise.
+func authStatus(err error) string {
+	switch {
+	case apierrors.IsUnauthorized(err):
+		return "401"
+	case apierrors.IsForbidden(err):
+		return "403"
+	default:
+		return ""
+	}
+}
+
+// probeContexts probes every context in kubeconfig, read from source, using a bounded pool of
+// workers, each cluster with its own timeout. Results are keyed by context name.
+func probeContexts(kubeconfig []byte, source string, contexts []string, timeout time.Duration) map[string]ClusterStatus {
+	results := make(map[string]ClusterStatus, len(contexts))
+
+	var mu sync.Mutex
+
+	jobs := make(chan string)
+
+	var wg sync.WaitGroup
+
+	for range min(probeWorkers, len(contexts)) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for name := range jobs {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				status := probeCluster(ctx, kubeconfig, source, name, timeout)
+
+				cancel()
+
+				mu.Lock()
+				results[name] = status
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, name := range contexts {
+		jobs <- name
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// checkCluster probes the cluster of a session kubeconfig generated from source when checks are
+// enabled. Unreachable clusters are reported as errors in strict mode and as warnings otherwise.
+func checkCluster(kubeconfig []byte, source, contextName string, cfg KswConfig, opts sessionOptions) error {
+	if !cfg.Check.Enabled && !opts.Check {
+		return nil
+	}
+
+	timeout := time.Duration(cfg.Check.Timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	status := probeCluster(ctx, kubeconfig, source, contextName, timeout)
+
+	switch {
+	case status.Reachable && status.Ready:
+		logf("context %s: %s", contextName, status)
+	case cfg.Check.Strict || opts.Check:
+		return fmt.Errorf("context %s: %s", contextName, status)
+	default:
+		logf("warning: context %s: %s", contextName, status)
+	}
+
+	return nil
+}
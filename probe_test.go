@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// newTestAPIServer starts a TLS server answering /version and /readyz like a Kubernetes API server.
+func newTestAPIServer(t *testing.T, ready bool) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"major":"1","minor":"31","gitVersion":"v1.31.2"}`))
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Unauthorized","code":401}`))
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"APIVersions","versions":["v1"]}`))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !ready {
+			http.Error(w, "etcd not ready", http.StatusInternalServerError)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// testSessionKubeconfig returns a kubeconfig pointing the "test" context at server.
+func testSessionKubeconfig(t *testing.T, server *httptest.Server) []byte {
+	t.Helper()
+
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	b, err := yaml.Marshal(apiv1.Config{
+		CurrentContext: "test",
+		Contexts:       []apiv1.NamedContext{{Name: "test", Context: apiv1.Context{Cluster: "test", AuthInfo: "test"}}},
+		Clusters:       []apiv1.NamedCluster{{Name: "test", Cluster: apiv1.Cluster{Server: server.URL, CertificateAuthorityData: caData}}},
+		AuthInfos:      []apiv1.NamedAuthInfo{{Name: "test", AuthInfo: apiv1.AuthInfo{Token: "token"}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal kubeconfig: %v", err)
+	}
+
+	return b
+}
+
+func TestProbeCluster(t *testing.T) {
+	t.Run("reachable and ready", func(t *testing.T) {
+		server := newTestAPIServer(t, true)
+
+		status := probeCluster(context.Background(), testSessionKubeconfig(t, server), "", "", 5*time.Second)
+		if !status.Reachable || !status.Ready {
+			t.Fatalf("probeCluster() = %+v, want reachable and ready", status)
+		}
+
+		if status.Version != "v1.31.2" {
+			t.Errorf("probeCluster() Version = %q, want v1.31.2", status.Version)
+		}
+	})
+
+	t.Run("rejected credentials", func(t *testing.T) {
+		server := newTestAPIServer(t, true)
+		kubeconfig := bytes.Replace(testSessionKubeconfig(t, server), []byte("token: token"), []byte("token: wrong"), 1)
+
+		status := probeCluster(context.Background(), kubeconfig, "", "", 5*time.Second)
+		if !status.Reachable || status.Auth != "401" {
+			t.Errorf("probeCluster() = %+v, want reachable with auth 401", status)
+		}
+	})
+
+	t.Run("rejected credentials without anonymous access", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403}`))
+		}))
+		t.Cleanup(server.Close)
+
+		status := probeCluster(context.Background(), testSessionKubeconfig(t, server), "", "", 5*time.Second)
+		if !status.Reachable || status.Auth != "403" {
+			t.Errorf("probeCluster() = %+v, want reachable with auth 403", status)
+		}
+
+		if !strings.Contains(status.String(), "credentials rejected (403)") {
+			t.Errorf("ClusterStatus.String() = %q", status.String())
+		}
+	})
+
+	t.Run("certificate authority relative to the source", func(t *testing.T) {
+		server := newTestAPIServer(t, true)
+		dir := t.TempDir()
+
+		caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		writeTestFile(t, filepath.Join(dir, "certs", "ca.crt"), string(caData))
+
+		kubeconfig, err := yaml.Marshal(apiv1.Config{
+			CurrentContext: "test",
+			Contexts:       []apiv1.NamedContext{{Name: "test", Context: apiv1.Context{Cluster: "test", AuthInfo: "test"}}},
+			Clusters:       []apiv1.NamedCluster{{Name: "test", Cluster: apiv1.Cluster{Server: server.URL, CertificateAuthority: "certs/ca.crt"}}},
+			AuthInfos:      []apiv1.NamedAuthInfo{{Name: "test", AuthInfo: apiv1.AuthInfo{Token: "token"}}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal kubeconfig: %v", err)
+		}
+
+		status := probeCluster(context.Background(), kubeconfig, filepath.Join(dir, "config"), "", 5*time.Second)
+		if !status.Reachable || !status.Ready {
+			t.Errorf("probeCluster() = %+v, want reachable and ready", status)
+		}
+	})
+
+	t.Run("reachable but not ready", func(t *testing.T) {
+		server := newTestAPIServer(t, false)
+
+		status := probeCluster(context.Background(), testSessionKubeconfig(t, server), "", "", 5*time.Second)
+		if !status.Reachable || status.Ready {
+			t.Errorf("probeCluster() = %+v, want reachable and not ready", status)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		server := newTestAPIServer(t, true)
+		kubeconfig := testSessionKubeconfig(t, server)
+		server.Close()
+
+		status := probeCluster(context.Background(), kubeconfig, "", "", 5*time.Second)
+		if status.Reachable || status.Err == nil {
+			t.Errorf("probeCluster() = %+v, want unreachable with error", status)
+		}
+
+		if !strings.HasPrefix(status.String(), "unreachable") {
+			t.Errorf("ClusterStatus.String() = %q", status.String())
+		}
+	})
+}
+
+func TestProbeContexts(t *testing.T) {
+	server := newTestAPIServer(t, true)
+	kubeconfig := testSessionKubeconfig(t, server)
+
+	statuses := probeContexts(kubeconfig, "", []string{"test", "missing"}, 5*time.Second)
+
+	if status := statuses["test"]; !status.Reachable || status.Auth != "OK" {
+		t.Errorf("probeContexts() test = %+v, want reachable with auth OK", status)
+	}
+
+	if status := statuses["missing"]; status.Reachable || status.Err == nil {
+		t.Errorf("probeContexts() missing = %+v, want error", status)
+	}
+}
+
+func TestCheckCluster(t *testing.T) {
+	server := newTestAPIServer(t, true)
+	kubeconfig := testSessionKubeconfig(t, server)
+	server.Close()
+
+	cfg := defaultConfig()
+
+	if err := checkCluster(kubeconfig, "", "test", cfg, sessionOptions{}); err != nil {
+		t.Errorf("checkCluster() with checks disabled error = %v", err)
+	}
+
+	cfg.Check.Enabled = true
+	if err := checkCluster(kubeconfig, "", "test", cfg, sessionOptions{}); err != nil {
+		t.Errorf("checkCluster() in warning mode error = %v", err)
+	}
+
+	if err := checkCluster(kubeconfig, "", "test", defaultConfig(), sessionOptions{Check: true}); err == nil {
+		t.Error("checkCluster() with --check expected error for unreachable cluster")
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/urfave/cli/v2"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// pruneDiff returns a diff deleting the given contexts together with the clusters and users
+// that no remaining context refers to. Clusters and users that were already unreferenced are kept.
+func pruneDiff(config apiv1.Config, contexts []string) KubeconfigDiff {
+	diff := KubeconfigDiff{ContextsDeleted: slices.Clone(contexts)}
+
+	usedClusters := make(map[string]bool)
+	usedUsers := make(map[string]bool)
+	freedClusters := make(map[string]bool)
+	freedUsers := make(map[string]bool)
+
+	for _, ctx := range config.Contexts {
+		if slices.Contains(contexts, ctx.Name) {
+			freedClusters[ctx.Context.Cluster] = true
+			freedUsers[ctx.Context.AuthInfo] = true
+		} else {
+			usedClusters[ctx.Context.Cluster] = true
+			usedUsers[ctx.Context.AuthInfo] = true
+		}
+	}
+
+	for _, x := range config.Clusters {
+		if freedClusters[x.Name] && !usedClusters[x.Name] {
+			diff.ClustersDeleted = append(diff.ClustersDeleted, x.Name)
+		}
+	}
+
+	for _, x := range config.AuthInfos {
+		if freedUsers[x.Name] && !usedUsers[x.Name] {
+			diff.UsersDeleted = append(diff.UsersDeleted, x.Name)
+		}
+	}
+
+	return diff
+}
+
+// unusedContexts returns the contexts of names that have not been entered since cutoff,
+// including the ones that were never recorded in history.
+func unusedContexts(names []string, history contextHistory, cutoff time.Time) []string {
+	var unused []string
+
+	for _, name := range names {
+		if lastUsed, ok := history.LastUsed[name]; !ok || lastUsed.Before(cutoff) {
+			unused = append(unused, name)
+		}
+	}
+
+	return unused
+}
+
+func pruneAction(c *cli.Context) error {
+	kubeconfigPath := getOriginalKubeconfigPath()
+
+	kubeconfigBytes, _, err := readKubeconfigBytes(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+
+	var config apiv1.Config
+	if err := yaml.Unmarshal(kubeconfigBytes, &config); err != nil {
+		return err
+	}
+
+	candidates := make([]string, 0, len(config.Contexts))
+	for _, ctx := range config.Contexts {
+		candidates = append(candidates, ctx.Name)
+	}
+
+	reasons := make(map[string][]string)
+
+	if days := c.Int("unused-days"); days > 0 {
+		history, err := loadHistory()
+		if err != nil {
+			return fmt.Errorf("failed to load context history: %w", err)
+		}
+
+		candidates = unusedContexts(candidates, history, time.Now().AddDate(0, 0, -days))
+
+		for _, name := range candidates {
+			if lastUsed, ok := history.LastUsed[name]; ok {
+				reasons[name] = append(reasons[name], "last used "+lastUsed.Local().Format("2006-01-02"))
+			} else {
+				reasons[name] = append(reasons[name], "never used")
+			}
+		}
+	}
+
+	if c.Bool("unreachable") && len(candidates) > 0 {
+		stop := startSpinner(fmt.Sprintf("probing %d cluster(s)", len(candidates)))
+		statuses := probeContexts(kubeconfigBytes, kubeconfigPath, candidates, time.Duration(loadConfig().Check.Timeout))
+
+		stop()
+
+		candidates = slices.DeleteFunc(candidates, func(name string) bool { return statuses[name].Reachable })
+
+		for _, name := range candidates {
+			reasons[name] = append(reasons[name], "unreachable")
+		}
+	}
+
+	if len(candidates) == 0 {
+		fmt.Println("No contexts to prune.")
+
+		return nil
+	}
+
+	items := make([]ChangeItem, 0, len(candidates))
+
+	for _, name := range candidates {
+		label := name
+		if len(reasons[name]) > 0 {
+			label += " (" + strings.Join(reasons[name], ", ") + ")"
+		}
+
+		items = append(items, ChangeItem{Type: ChangeContext, Action: ActionDelete, Name: label, Value: name})
+	}
+
+	fmt.Printf("Select the contexts to remove from %s:\n", kubeconfigPath)
+	fmt.Println("(Use Up/Down arrows to move, Space to toggle, Enter to confirm, Esc to cancel.)")
+	fmt.Println()
+
+	checked, cancelled, err := runTUI(items)
+	if err != nil {
+		return fmt.Errorf("error during interactive selection: %w", err)
+	}
+
+	var selected []string
+
+	if !cancelled {
+		for i, isChecked := range checked {
+			if isChecked {
+				selected = append(selected, items[i].Value.(string))
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		fmt.Println("No contexts removed.")
+
+		return nil
+	}
+
+	diff := pruneDiff(config, selected)
+
+	fmt.Printf("\nThe following will be removed from %s:\n", kubeconfigPath)
+
+	for _, item := range diff.ToChangeItems() {
+		fmt.Printf("  - %s %s\n", item.Type, item.Name)
+	}
+
+	remove, err := confirm("Remove them?")
+	if err != nil {
+		return fmt.Errorf("error confirming changes: %w", err)
+	}
+
+	if !remove {
+		fmt.Println("No contexts removed.")
+
+		return nil
+	}
+
+	applied, err := applyChangesToOriginal(kubeconfigPath, diff)
+	if err != nil || !applied {
+		return err
+	}
+
+	fmt.Printf("Removed %d context(s). The previous version was saved to %s.\n", len(selected), backupPath(kubeconfigPath))
+
+	return nil
+}
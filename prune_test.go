@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+func TestPruneDiff(t *testing.T) {
+	config := apiv1.Config{
+		Contexts: []apiv1.NamedContext{
+			{Name: "old", Context: apiv1.Context{Cluster: "old-cluster", AuthInfo: "shared-user"}},
+			{Name: "old-admin", Context: apiv1.Context{Cluster: "old-cluster", AuthInfo: "old-admin"}},
+			{Name: "current", Context: apiv1.Context{Cluster: "current-cluster", AuthInfo: "shared-user"}},
+		},
+		Clusters: []apiv1.NamedCluster{
+			{Name: "current-cluster"},
+			{Name: "old-cluster"},
+			{Name: "orphan-cluster"},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "old-admin"},
+			{Name: "orphan-user"},
+			{Name: "shared-user"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		contexts []string
+		want     KubeconfigDiff
+	}{
+		{
+			name:     "cluster still referenced",
+			contexts: []string{"old"},
+			want:     KubeconfigDiff{ContextsDeleted: []string{"old"}},
+		},
+		{
+			name:     "cluster and user freed",
+			contexts: []string{"old", "old-admin"},
+			want: KubeconfigDiff{
+				ContextsDeleted: []string{"old", "old-admin"},
+				ClustersDeleted: []string{"old-cluster"},
+				UsersDeleted:    []string{"old-admin"},
+			},
+		},
+		{
+			name:     "everything",
+			contexts: []string{"old", "old-admin", "current"},
+			want: KubeconfigDiff{
+				ContextsDeleted: []string{"old", "old-admin", "current"},
+				ClustersDeleted: []string{"current-cluster", "old-cluster"},
+				UsersDeleted:    []string{"old-admin", "shared-user"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pruneDiff(config, tt.contexts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pruneDiff() = %+v, want %+v", got, tt.want)
+			}
+
+			if dangling := newDanglingReferences(config, applyDiff(config, got)); len(dangling) > 0 {
+				t.Errorf("pruneDiff() leaves dangling references: %v", dangling)
+			}
+		})
+	}
+}
+
+func TestUnusedContexts(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	history := contextHistory{LastUsed: map[string]time.Time{
+		"recent": now.AddDate(0, 0, -1),
+		"stale":  now.AddDate(0, 0, -100),
+	}}
+
+	got := unusedContexts([]string{"recent", "stale", "never"}, history, now.AddDate(0, 0, -30))
+
+	want := []string{"stale", "never"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unusedContexts() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/url"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// redactedValue replaces secret values in rendered kubeconfig and ksw config content.
+const redactedValue = "REDACTED"
+
+// redactURL hides the password of a URL with embedded credentials.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), redactedValue)
+	}
+
+	return u.String()
+}
+
+// redactCluster returns a copy of c with credentials embedded in its URLs hidden.
+func redactCluster(c apiv1.Cluster) apiv1.Cluster {
+	c.Server = redactURL(c.Server)
+	c.ProxyURL = redactURL(c.ProxyURL)
+
+	return c
+}
+
+// redactAuthInfo returns a copy of a with tokens, passwords, client keys,
+// auth provider secrets and exec plugin env values hidden.
+func redactAuthInfo(a apiv1.AuthInfo) apiv1.AuthInfo {
+	if len(a.ClientKeyData) > 0 {
+		a.ClientKeyData = []byte(redactedValue)
+	}
+
+	if a.Token != "" {
+		a.Token = redactedValue
+	}
+
+	if a.Password != "" {
+		a.Password = redactedValue
+	}
+
+	if a.AuthProvider != nil {
+		provider := *a.AuthProvider
+		provider.Config = make(map[string]string, len(a.AuthProvider.Config))
+
+		for k, v := range a.AuthProvider.Config {
+			lower := strings.ToLower(k)
+			if strings.Contains(lower, "token") || strings.Contains(lower, "secret") {
+				v = redactedValue
+			}
+
+			provider.Config[k] = v
+		}
+
+		a.AuthProvider = &provider
+	}
+
+	if a.Exec != nil {
+		plugin := *a.Exec
+		plugin.Env = make([]apiv1.ExecEnvVar, len(a.Exec.Env))
+
+		for i, env := range a.Exec.Env {
+			plugin.Env[i] = apiv1.ExecEnvVar{Name: env.Name, Value: redactedValue}
+		}
+
+		a.Exec = &plugin
+	}
+
+	return a
+}
+
+// redactConfig returns a copy of c with secrets hidden in every cluster and user.
+func redactConfig(c apiv1.Config) apiv1.Config {
+	clusters := make([]apiv1.NamedCluster, len(c.Clusters))
+	for i, x := range c.Clusters {
+		clusters[i] = apiv1.NamedCluster{Name: x.Name, Cluster: redactCluster(x.Cluster)}
+	}
+
+	users := make([]apiv1.NamedAuthInfo, len(c.AuthInfos))
+	for i, x := range c.AuthInfos {
+		users[i] = apiv1.NamedAuthInfo{Name: x.Name, AuthInfo: redactAuthInfo(x.AuthInfo)}
+	}
+
+	c.Clusters = clusters
+	c.AuthInfos = users
+
+	return c
+}
+
+// redactKswConfig returns a copy of c with the env values of every context hidden, since they
+// often hold tokens or passwords.
+func redactKswConfig(c KswConfig) KswConfig {
+	c = c.clone()
+
+	for name, ctx := range c.Contexts {
+		if len(ctx.Env) == 0 {
+			continue
+		}
+
+		env := make(EnvVars, len(ctx.Env))
+		for k := range ctx.Env {
+			env[k] = redactedValue
+		}
+
+		ctx.Env = env
+		c.Contexts[name] = ctx
+	}
+
+	return c
+}
+
+// renderConfig marshals c to YAML for display, redacting secrets unless showSecrets is set.
+// Every code path that prints kubeconfig content should go through this function.
+func renderConfig(c apiv1.Config, showSecrets bool) (string, error) {
+	if !showSecrets {
+		c = redactConfig(c)
+	}
+
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	if !showSecrets {
+		// Byte fields are base64 encoded on output, so unwrap the placeholder for readability
+		encoded := base64.StdEncoding.EncodeToString([]byte(redactedValue))
+		b = bytes.ReplaceAll(b, []byte(encoded), []byte(redactedValue))
+	}
+
+	return string(b), nil
+}
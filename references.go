@@ -0,0 +1,103 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+// DanglingReference describes a context that refers to a cluster or user missing from the config.
+type DanglingReference struct {
+	Context string
+	Type    ChangeItemType
+	Name    string
+}
+
+// String returns a human readable description of the dangling reference.
+func (r DanglingReference) String() string {
+	return fmt.Sprintf("context %q references missing %s %q", r.Context, r.Type, r.Name)
+}
+
+// checkReferences returns every context reference in c that points at a cluster or user
+// not defined in c. Empty references are not reported.
+func checkReferences(c apiv1.Config) []DanglingReference {
+	clusters := clustersMap(c.Clusters)
+	users := usersMap(c.AuthInfos)
+
+	var dangling []DanglingReference
+
+	for _, ctx := range c.Contexts {
+		if _, exists := clusters[ctx.Context.Cluster]; ctx.Context.Cluster != "" && !exists {
+			dangling = append(dangling, DanglingReference{Context: ctx.Name, Type: ChangeCluster, Name: ctx.Context.Cluster})
+		}
+
+		if _, exists := users[ctx.Context.AuthInfo]; ctx.Context.AuthInfo != "" && !exists {
+			dangling = append(dangling, DanglingReference{Context: ctx.Name, Type: ChangeUser, Name: ctx.Context.AuthInfo})
+		}
+	}
+
+	slices.SortFunc(dangling, func(a, b DanglingReference) int {
+		return cmp.Or(cmp.Compare(a.Context, b.Context), cmp.Compare(a.Type, b.Type))
+	})
+
+	return dangling
+}
+
+// newDanglingReferences returns the dangling references in after that were not already present in before,
+// so that pre-existing problems in the original kubeconfig do not block a merge.
+func newDanglingReferences(before, after apiv1.Config) []DanglingReference {
+	existing := checkReferences(before)
+
+	var introduced []DanglingReference
+
+	for _, r := range checkReferences(after) {
+		if !slices.Contains(existing, r) {
+			introduced = append(introduced, r)
+		}
+	}
+
+	return introduced
+}
+
+// referenceDependencies returns the indexes of the new or renamed clusters and users
+// that the context at items[i] refers to, so they can be selected together with it.
+func referenceDependencies(items []ChangeItem, i int) []int {
+	ctx, ok := items[i].Value.(apiv1.NamedContext)
+	if !ok {
+		return nil
+	}
+
+	var deps []int
+
+	for j, item := range items {
+		var ref string
+
+		switch item.Type {
+		case ChangeCluster:
+			ref = ctx.Context.Cluster
+		case ChangeUser:
+			ref = ctx.Context.AuthInfo
+		default:
+			continue
+		}
+
+		switch v := item.Value.(type) {
+		case apiv1.NamedCluster:
+			if item.Action == ActionAdd && v.Name == ref {
+				deps = append(deps, j)
+			}
+		case apiv1.NamedAuthInfo:
+			if item.Action == ActionAdd && v.Name == ref {
+				deps = append(deps, j)
+			}
+		case KubeconfigRename:
+			if v.To == ref {
+				deps = append(deps, j)
+			}
+		}
+	}
+
+	return deps
+}
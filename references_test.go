@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+)
+
+func TestCheckReferences(t *testing.T) {
+	config := apiv1.Config{
+		Contexts: []apiv1.NamedContext{
+			{Name: "ok", Context: apiv1.Context{Cluster: "cluster1", AuthInfo: "user1"}},
+			{Name: "no-user", Context: apiv1.Context{Cluster: "cluster1"}},
+			{Name: "missing-both", Context: apiv1.Context{Cluster: "cluster2", AuthInfo: "user2"}},
+			{Name: "missing-user", Context: apiv1.Context{Cluster: "cluster1", AuthInfo: "user3"}},
+		},
+		Clusters: []apiv1.NamedCluster{
+			{Name: "cluster1", Cluster: apiv1.Cluster{Server: "https://c1.example.com"}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "user1", AuthInfo: apiv1.AuthInfo{Token: "token1"}},
+		},
+	}
+
+	got := checkReferences(config)
+
+	want := []DanglingReference{
+		{Context: "missing-both", Type: ChangeCluster, Name: "cluster2"},
+		{Context: "missing-both", Type: ChangeUser, Name: "user2"},
+		{Context: "missing-user", Type: ChangeUser, Name: "user3"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkReferences() = %v, want %v", got, want)
+	}
+}
+
+func TestNewDanglingReferences(t *testing.T) {
+	before := apiv1.Config{
+		Contexts: []apiv1.NamedContext{
+			{Name: "c1", Context: apiv1.Context{Cluster: "cluster1", AuthInfo: "user1"}},
+			{Name: "broken", Context: apiv1.Context{Cluster: "gone", AuthInfo: "user1"}},
+		},
+		Clusters: []apiv1.NamedCluster{
+			{Name: "cluster1", Cluster: apiv1.Cluster{Server: "https://c1.example.com"}},
+		},
+		AuthInfos: []apiv1.NamedAuthInfo{
+			{Name: "user1", AuthInfo: apiv1.AuthInfo{Token: "token1"}},
+		},
+	}
+
+	after := applyDiff(before, KubeconfigDiff{
+		ContextsAdded: []apiv1.NamedContext{
+			{Name: "c2", Context: apiv1.Context{Cluster: "cluster2", AuthInfo: "user1"}},
+		},
+		UsersDeleted: []string{"user1"},
+	})
+
+	got := newDanglingReferences(before, after)
+
+	want := []DanglingReference{
+		{Context: "broken", Type: ChangeUser, Name: "user1"},
+		{Context: "c1", Type: ChangeUser, Name: "user1"},
+		{Context: "c2", Type: ChangeCluster, Name: "cluster2"},
+		{Context: "c2", Type: ChangeUser, Name: "user1"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newDanglingReferences() = %v, want %v", got, want)
+	}
+}
+
+func TestReferenceDependencies(t *testing.T) {
+	items := KubeconfigDiff{
+		ContextsAdded: []apiv1.NamedContext{
+			{Name: "c2", Context: apiv1.Context{Cluster: "cluster2", AuthInfo: "user2-new"}},
+		},
+		ClustersAdded: []apiv1.NamedCluster{
+			{Name: "cluster2", Cluster: apiv1.Cluster{Server: "https://c2.example.com"}},
+			{Name: "cluster3", Cluster: apiv1.Cluster{Server: "https://c3.example.com"}},
+		},
+		UsersRenamed: []KubeconfigRename{{From: "user2", To: "user2-new"}},
+	}.ToChangeItems()
+
+	got := referenceDependencies(items, 0)
+
+	want := []int{1, 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("referenceDependencies() = %v, want %v", got, want)
+	}
+
+	if deps := referenceDependencies(items, 1); deps != nil {
+		t.Errorf("referenceDependencies() for a cluster = %v, want nil", deps)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// defaultSessionDir returns the directory session kubeconfigs are written to when none is configured.
+//
+// On Linux it prefers $XDG_RUNTIME_DIR/ksw, which is a user-only tmpfs that is never persisted to disk.
+// Elsewhere, or when XDG_RUNTIME_DIR is unset, it falls back to a per-user directory in the temp dir.
+func defaultSessionDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "ksw")
+	}
+
+	if runtime.GOOS == "linux" {
+		logf("warning: XDG_RUNTIME_DIR is not set, session kubeconfigs will be stored in %s", os.TempDir())
+	}
+
+	return filepath.Join(os.TempDir(), "ksw-"+strconv.Itoa(os.Getuid()))
+}
+
+// sessionDir returns the directory for session kubeconfigs, creating it if needed
+// and verifying that only the current user can access it.
+func sessionDir(cfg KswConfig) (string, error) {
+	dir := cfg.Session.Dir
+	if dir == "" {
+		dir = defaultSessionDir()
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	if err := verifyPrivateDir(dir); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// verifyPrivateDir checks that dir is a real directory owned by the current user with no group or other access.
+func verifyPrivateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("session directory %s is not a directory", dir)
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("session directory %s is owned by uid %d, not the current user", dir, stat.Uid)
+	}
+
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("session directory %s has permissions %04o, expected 0700", dir, perm)
+	}
+
+	return nil
+}
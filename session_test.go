@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionDir(t *testing.T) {
+	t.Run("defaults to XDG_RUNTIME_DIR", func(t *testing.T) {
+		runtimeDir := t.TempDir()
+		t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+		got, err := sessionDir(defaultConfig())
+		if err != nil {
+			t.Fatalf("sessionDir() error = %v", err)
+		}
+
+		if want := filepath.Join(runtimeDir, "ksw"); got != want {
+			t.Errorf("sessionDir() = %v, want %v", got, want)
+		}
+
+		info, err := os.Stat(got)
+		if err != nil {
+			t.Fatalf("Failed to stat session dir: %v", err)
+		}
+
+		if perm := info.Mode().Perm(); perm != 0700 {
+			t.Errorf("sessionDir() created directory with permissions %04o, want 0700", perm)
+		}
+	})
+
+	t.Run("configured directory", func(t *testing.T) {
+		cfg := defaultConfig()
+		cfg.Session.Dir = filepath.Join(t.TempDir(), "sessions")
+
+		got, err := sessionDir(cfg)
+		if err != nil {
+			t.Fatalf("sessionDir() error = %v", err)
+		}
+
+		if got != cfg.Session.Dir {
+			t.Errorf("sessionDir() = %v, want %v", got, cfg.Session.Dir)
+		}
+	})
+
+	t.Run("rejects world accessible directory", func(t *testing.T) {
+		cfg := defaultConfig()
+		cfg.Session.Dir = filepath.Join(t.TempDir(), "sessions")
+
+		if err := os.Mkdir(cfg.Session.Dir, 0700); err != nil {
+			t.Fatalf("Failed to create session dir: %v", err)
+		}
+
+		if err := os.Chmod(cfg.Session.Dir, 0755); err != nil {
+			t.Fatalf("Failed to chmod session dir: %v", err)
+		}
+
+		if _, err := sessionDir(cfg); err == nil {
+			t.Error("sessionDir() expected error for directory with 0755 permissions")
+		}
+	})
+
+	t.Run("rejects symlink", func(t *testing.T) {
+		target := t.TempDir()
+		cfg := defaultConfig()
+		cfg.Session.Dir = filepath.Join(t.TempDir(), "link")
+
+		if err := os.Symlink(target, cfg.Session.Dir); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+
+		if _, err := sessionDir(cfg); err == nil {
+			t.Error("sessionDir() expected error for symlinked directory")
+		}
+	})
+}
@@ -1,39 +1,72 @@
 package main
 
 import (
+	"cmp"
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"syscall"
+	"time"
+
+	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
 )
 
+// sessionOptions holds command line overrides for starting or switching a session.
+type sessionOptions struct {
+	// Check refuses to enter a context whose cluster is unreachable.
+	Check bool
+	// Namespace overrides the namespace of the context when not empty.
+	Namespace string
+}
+
 // startShell creates a new ksw session by generating a minified kubeconfig
 // and replacing the current process with the user's shell using syscall.Exec.
 //
-// It loads the original kubeconfig from KSW_KUBECONFIG_ORIGINAL, KUBECONFIG,
-// or $HOME/.kube/config (in that order), minifies it to include only the
-// specified context, writes it to a temporary file, and sets up environment
-// variables before executing the shell.
+// It loads the original kubeconfig resolved by getOriginalKubeconfigPath, minifies it
+// to include only the specified context, writes it to a file in the private session
+// directory, and sets up environment variables before executing the shell.
 //
 // The ksw process is replaced entirely, so this function never returns on success.
-// Temporary kubeconfig files are cleaned up by the OS temp directory cleanup.
-func startShell(shell, contextName string) error {
-	var kubeconfigOriginal string // TODO add condition to use original kubeconfig from cli flags
-	if path := os.Getenv("KSW_KUBECONFIG_ORIGINAL"); path != "" {
-		kubeconfigOriginal = path
-	} else if path := os.Getenv("KUBECONFIG"); path != "" {
-		kubeconfigOriginal = path
-	} else {
-		kubeconfigOriginal = filepath.Join(os.Getenv("HOME"), ".kube", "config")
-	}
-
-	b, err := generateKubeconfig(kubeconfigOriginal, contextName)
+// Session kubeconfig files live in $XDG_RUNTIME_DIR/ksw by default, which is
+// cleared when the user logs out.
+func startShell(shell, contextName string, opts sessionOptions) error {
+	kubeconfigOriginal := getOriginalKubeconfigPath()
+
+	cfg := loadConfig()
+	ctxCfg := cfg.contextConfig(contextName)
+
+	if err := confirmProtected(contextName, ctxCfg); err != nil {
+		return err
+	}
+
+	original, enc, err := readKubeconfigWithEncryption(kubeconfigOriginal)
 	if err != nil {
 		return err
 	}
 
-	f, err := os.CreateTemp("", fmt.Sprintf("%s.*.yaml", contextName))
+	b, err := sessionKubeconfig(original, enc, contextName, cfg)
+	if err != nil {
+		return err
+	}
+
+	if b, err = setContextNamespace(b, contextName, cmp.Or(opts.Namespace, ctxCfg.Namespace)); err != nil {
+		return err
+	}
+
+	warnCredentialExpiry(original, contextName, cfg)
+
+	prefetchExecCredentials(kubeconfigOriginal, original, contextName, cfg)
+
+	if err := checkCluster(b, kubeconfigOriginal, contextName, cfg, opts); err != nil {
+		return err
+	}
+
+	dir, err := sessionDir(cfg)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, fmt.Sprintf("%s.*.yaml", contextName))
 	if err != nil {
 		return err
 	}
@@ -51,11 +84,20 @@ func startShell(shell, contextName string) error {
 	_ = os.Setenv("KSW_KUBECONFIG", f.Name())
 	_ = os.Setenv("KSW_ACTIVE", "true")
 	_ = os.Setenv("KSW_SHELL", shell)
+	// The env file belongs to the shell ksw was started from, not to the session shell
+	_ = os.Unsetenv("KSW_ENV_FILE")
+
+	for k, v := range ctxCfg.Env {
+		_ = os.Setenv(k, v)
+	}
+
+	runHook(shell, "on_enter", ctxCfg.OnEnter, os.Environ())
+	recordContextUse(contextName, time.Now())
 
 	logf("starting shell for context %s", contextName)
 
-	cfg := loadConfig()
-	if cfg.Kubeconfig.MergeOnExit.Enabled {
+	// Stay around as the parent process when something has to happen after the shell exits
+	if cfg.hasMergeOnExit() || hasExitHooks(cfg) {
 		// Spawn shell as a child process
 		cmd := exec.Command(shell)
 		cmd.Stdin = os.Stdin
@@ -65,9 +107,17 @@ func startShell(shell, contextName string) error {
 
 		shellErr := cmd.Run()
 
+		// The session may have switched contexts, so the exit hook and merge policy are the last one's
+		lastContext := sessionCurrentContext(f.Name())
+		if lastContext != "" {
+			runHook(shell, "on_exit", cfg.contextConfig(lastContext).OnExit, os.Environ())
+		}
+
 		// Merge temporary changes back
-		if err := mergeOnExit(kubeconfigOriginal, f.Name(), cfg.Kubeconfig.Minify); err != nil {
-			logf("error merging kubeconfig changes: %v", err)
+		if cfg.mergeOnExit(lastContext) {
+			if err := mergeOnExit(kubeconfigOriginal, f.Name(), shouldMinify(cfg, enc, lastContext)); err != nil {
+				logf("error merging kubeconfig changes: %v", err)
+			}
 		}
 
 		// Clean up temporary kubeconfig file
@@ -79,7 +129,7 @@ func startShell(shell, contextName string) error {
 	}
 
 	// Replace ksw process with shell
-	// Temp file cleanup relies on OS temp directory cleanup
+	// Session file cleanup relies on the session directory being cleared on logout
 	if err := syscall.Exec(shell, []string{shell}, os.Environ()); err != nil {
 		return fmt.Errorf("failed to exec shell: %w", err)
 	}
@@ -95,29 +145,121 @@ func startShell(shell, contextName string) error {
 // new context without requiring a new shell or process.
 //
 // This approach avoids nested shells and keeps the same process tree level.
-func switchContext(contextName string) error {
+func switchContext(contextName string, opts sessionOptions) error {
 	kubeconfigOriginal := os.Getenv("KSW_KUBECONFIG_ORIGINAL")
 	if kubeconfigOriginal == "" {
 		return fmt.Errorf("KSW_KUBECONFIG_ORIGINAL not set, cannot switch context")
 	}
 
+	if kubeconfigFlag != "" && kubeconfigFlag != kubeconfigOriginal {
+		return fmt.Errorf("session uses %s, cannot switch to a context of %s", kubeconfigOriginal, kubeconfigFlag)
+	}
+
 	existingKubeconfig := os.Getenv("KSW_KUBECONFIG")
 	if existingKubeconfig == "" {
 		return fmt.Errorf("KSW_KUBECONFIG not set, cannot switch context")
 	}
 
-	b, err := generateKubeconfig(kubeconfigOriginal, contextName)
+	cfg := loadConfig()
+	next := cfg.contextConfig(contextName)
+
+	if err := confirmProtected(contextName, next); err != nil {
+		return err
+	}
+
+	// The original kubeconfig is only read, and decrypted, once per switch
+	original, enc, err := readKubeconfigWithEncryption(kubeconfigOriginal)
 	if err != nil {
 		return err
 	}
 
+	b, err := sessionKubeconfig(original, enc, contextName, cfg)
+	if err != nil {
+		return err
+	}
+
+	if b, err = setContextNamespace(b, contextName, cmp.Or(opts.Namespace, next.Namespace)); err != nil {
+		return err
+	}
+
+	warnCredentialExpiry(original, contextName, cfg)
+
+	shell := hookShell()
+
+	prefetchExecCredentials(kubeconfigOriginal, original, contextName, cfg)
+
+	if err := checkCluster(b, kubeconfigOriginal, contextName, cfg, opts); err != nil {
+		return err
+	}
+
+	var prev ContextConfig
+	if prevContext := sessionCurrentContext(existingKubeconfig); prevContext != "" {
+		prev = cfg.contextConfig(prevContext)
+	}
+
+	runHook(shell, "on_exit", prev.OnExit, os.Environ())
+
 	// Overwrite existing temp file with new context
 	if err := os.WriteFile(existingKubeconfig, b, 0600); err != nil {
 		return err
 	}
 
+	runHook(shell, "on_enter", next.OnEnter, contextEnviron(os.Environ(), prev.Env, next.Env))
+	recordContextUse(contextName, time.Now())
+
+	if err := emitContextEnv(prev.Env, next.Env); err != nil {
+		return err
+	}
+
 	logf("switched to context %s", contextName)
 
 	// No process spawning - kubectl will immediately see the new context
 	return nil
 }
+
+// confirmProtected asks before entering a context protected by the contexts section of the config.
+func confirmProtected(contextName string, ctxCfg ContextConfig) error {
+	if !ctxCfg.Protected {
+		return nil
+	}
+
+	if !canPrompt() {
+		return fmt.Errorf("%s is protected, refusing to enter without a terminal", contextName)
+	}
+
+	ok, err := confirm(fmt.Sprintf("%s is a protected context, enter it?", contextName))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("not entering protected context %s", contextName)
+	}
+
+	return nil
+}
+
+// hasExitHooks reports whether any context defines an on_exit hook.
+func hasExitHooks(cfg KswConfig) bool {
+	for _, c := range cfg.Contexts {
+		if c.OnExit != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// warnCredentialExpiry logs a warning when the client certificate or token used by contextName
+// in config has expired or expires within the configured warning window.
+func warnCredentialExpiry(config apiv1.Config, contextName string, cfg KswConfig) {
+	expiry, ok := contextCredentialExpiry(config, contextName)
+	if !ok {
+		return
+	}
+
+	window := time.Duration(cfg.Credentials.ExpiryWarning)
+	if msg := expiryWarning(contextName, expiry, window, time.Now()); msg != "" {
+		logf("warning: %s", msg)
+	}
+}
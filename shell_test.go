@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConfirmProtected(t *testing.T) {
+	origCanPrompt := canPrompt
+	origStdin := os.Stdin
+
+	t.Cleanup(func() {
+		canPrompt = origCanPrompt
+		os.Stdin = origStdin
+	})
+
+	if err := confirmProtected("dev", ContextConfig{}); err != nil {
+		t.Errorf("confirmProtected() error = %v for an unprotected context", err)
+	}
+
+	canPrompt = func() bool { return false }
+
+	err := confirmProtected("prod", ContextConfig{Protected: true})
+	if err == nil || !strings.Contains(err.Error(), "without a terminal") {
+		t.Errorf("confirmProtected() error = %v, want a refusal without a terminal", err)
+	}
+
+	canPrompt = func() bool { return true }
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "yes", input: "y\n"},
+		{name: "no", input: "n\n", wantErr: true},
+		{name: "closed stdin", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Fatalf("os.Pipe() error = %v", err)
+			}
+
+			_, _ = w.WriteString(tt.input)
+			_ = w.Close()
+
+			os.Stdin = r
+
+			err = confirmProtected("prod", ContextConfig{Protected: true})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("confirmProtected() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil && !strings.Contains(err.Error(), "not entering") {
+				t.Errorf("confirmProtected() error = %v, want it to decline", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+)
+
+// posixShellInit wraps the ksw binary in a shell function so that environment changes
+// emitted while switching contexts inside a session are applied to the current shell.
+const posixShellInit = `ksw() {
+  local ksw_env_file ksw_status
+  ksw_env_file="$(mktemp "${TMPDIR:-/tmp}/ksw-env.XXXXXX")" || return
+  KSW_ENV_FILE="$ksw_env_file" command ksw "$@"
+  ksw_status=$?
+  if [ -s "$ksw_env_file" ]; then
+    . "$ksw_env_file"
+  fi
+  rm -f "$ksw_env_file"
+  return $ksw_status
+}
+`
+
+// bashCdHook runs 'ksw hook' from the prompt whenever the working directory of a session changes.
+const bashCdHook = `_ksw_hook() {
+  if [ -n "$KSW_ACTIVE" ] && [ "$PWD" != "$_ksw_last_pwd" ]; then
+    _ksw_last_pwd="$PWD"
+    ksw hook
+  fi
+}
+case ";${PROMPT_COMMAND:-};" in
+  *";_ksw_hook;"*) ;;
+  *) PROMPT_COMMAND="_ksw_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`
+
+// zshCdHook runs 'ksw hook' whenever a session changes directory, and once when it starts.
+const zshCdHook = `_ksw_hook() {
+  if [ -n "$KSW_ACTIVE" ]; then
+    ksw hook
+  fi
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _ksw_hook
+_ksw_hook
+`
+
+var shellInitScripts = map[string]string{
+	"bash": posixShellInit + bashCdHook,
+	"zsh":  posixShellInit + zshCdHook,
+}
+
+// shellName returns the name of a supported shell from its path, defaulting to bash.
+func shellName(shellPath string) string {
+	name := filepath.Base(shellPath)
+	if _, ok := shellInitScripts[name]; ok {
+		return name
+	}
+
+	return "bash"
+}
+
+func initAction(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return fmt.Errorf("shell name required: bash or zsh")
+	}
+
+	script, ok := shellInitScripts[name]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q: expected bash or zsh", name)
+	}
+
+	fmt.Print(script)
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// tagQueryPrefix marks tag filters in a finder query, such as "tag:env=prod".
+const tagQueryPrefix = "tag:"
+
+// untaggedGroup is the group of contexts without the tag a listing is grouped by.
+const untaggedGroup = "(untagged)"
+
+// contextTags returns the tags of contextName: the named captures of matching tag_patterns,
+// overridden by the tags of matching contexts entries.
+func (c KswConfig) contextTags(contextName string) map[string]string {
+	tags := make(map[string]string)
+
+	for _, pattern := range c.TagPatterns {
+		re, err := regexp.Compile(string(pattern))
+		if err != nil {
+			continue
+		}
+
+		m := re.FindStringSubmatch(contextName)
+		if m == nil {
+			continue
+		}
+
+		for i, name := range re.SubexpNames() {
+			if name != "" && m[i] != "" {
+				tags[name] = m[i]
+			}
+		}
+	}
+
+	maps.Copy(tags, c.contextConfig(contextName).Tags)
+
+	return tags
+}
+
+// tagFilter selects contexts by tag. Without a key it matches any tag with the value.
+type tagFilter struct {
+	Key   string
+	Value string
+}
+
+// parseTagFilter parses "key=value", or a bare value matching any tag.
+func parseTagFilter(s string) tagFilter {
+	if key, value, ok := strings.Cut(s, "="); ok {
+		return tagFilter{Key: key, Value: value}
+	}
+
+	return tagFilter{Value: s}
+}
+
+func (f tagFilter) matches(tags map[string]string) bool {
+	if f.Key != "" {
+		return tags[f.Key] == f.Value
+	}
+
+	for _, v := range tags {
+		if v == f.Value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitTagQuery separates the "tag:" filters of a finder query from the rest of it.
+func splitTagQuery(query string) (string, []tagFilter) {
+	var (
+		words   []string
+		filters []tagFilter
+	)
+
+	for _, word := range strings.Fields(query) {
+		if value, ok := strings.CutPrefix(word, tagQueryPrefix); ok && value != "" {
+			filters = append(filters, parseTagFilter(value))
+		} else {
+			words = append(words, word)
+		}
+	}
+
+	return strings.Join(words, " "), filters
+}
+
+// filterContextsByTags returns the contexts matching every filter.
+func filterContextsByTags(cfg KswConfig, contexts []string, filters []tagFilter) []string {
+	if len(filters) == 0 {
+		return contexts
+	}
+
+	var matched []string
+
+	for _, ctx := range contexts {
+		tags := cfg.contextTags(ctx)
+
+		if !slices.ContainsFunc(filters, func(f tagFilter) bool { return !f.matches(tags) }) {
+			matched = append(matched, ctx)
+		}
+	}
+
+	return matched
+}
+
+// formatTags formats tags as "key=value" pairs in key order.
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for _, k := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, tags[k]))
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+// contextLabel is how a context is shown in the finder, with its tags so that they can be searched.
+func contextLabel(cfg KswConfig, contextName string) string {
+	tags := cfg.contextTags(contextName)
+	if len(tags) == 0 {
+		return contextName
+	}
+
+	return fmt.Sprintf("%s  [%s]", contextName, formatTags(tags))
+}
+
+// contextGroup is a set of contexts sharing the value of a tag.
+type contextGroup struct {
+	Name     string
+	Contexts []string
+}
+
+// groupContextsByTag groups contexts by the value of the tag key, in value order, followed by
+// the contexts without that tag. Contexts keep their order within a group.
+func groupContextsByTag(cfg KswConfig, contexts []string, key string) []contextGroup {
+	byValue := make(map[string][]string)
+
+	var untagged []string
+
+	for _, ctx := range contexts {
+		if value, ok := cfg.contextTags(ctx)[key]; ok {
+			byValue[value] = append(byValue[value], ctx)
+		} else {
+			untagged = append(untagged, ctx)
+		}
+	}
+
+	groups := make([]contextGroup, 0, len(byValue)+1)
+	for _, value := range slices.Sorted(maps.Keys(byValue)) {
+		groups = append(groups, contextGroup{Name: fmt.Sprintf("%s=%s", key, value), Contexts: byValue[value]})
+	}
+
+	if len(untagged) > 0 {
+		groups = append(groups, contextGroup{Name: untaggedGroup, Contexts: untagged})
+	}
+
+	return groups
+}
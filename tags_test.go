@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testTagConfig = KswConfig{
+	TagPatterns: []Regexp{`^(?P<env>prod|staging|dev)(-(?P<region>\w+))?$`},
+	Contexts: map[string]ContextConfig{
+		"prod-*":  {Tags: map[string]string{"team": "payments"}},
+		"prod-us": {Tags: map[string]string{"region": "us-east"}},
+	},
+}
+
+func TestContextTags(t *testing.T) {
+	tests := []struct {
+		context string
+		want    map[string]string
+	}{
+		{context: "dev", want: map[string]string{"env": "dev"}},
+		{context: "prod-eu", want: map[string]string{"env": "prod", "region": "eu", "team": "payments"}},
+		{context: "prod-us", want: map[string]string{"env": "prod", "region": "us-east", "team": "payments"}},
+		{context: "minikube", want: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.context, func(t *testing.T) {
+			if got := testTagConfig.contextTags(tt.context); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("contextTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitTagQuery(t *testing.T) {
+	tests := []struct {
+		query       string
+		wantQuery   string
+		wantFilters []tagFilter
+	}{
+		{query: "prod", wantQuery: "prod"},
+		{query: "tag:env=prod eu", wantQuery: "eu", wantFilters: []tagFilter{{Key: "env", Value: "prod"}}},
+		{query: "tag:payments tag:", wantQuery: "tag:", wantFilters: []tagFilter{{Value: "payments"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, filters := splitTagQuery(tt.query)
+			if query != tt.wantQuery || !reflect.DeepEqual(filters, tt.wantFilters) {
+				t.Errorf("splitTagQuery() = %q, %v, want %q, %v", query, filters, tt.wantQuery, tt.wantFilters)
+			}
+		})
+	}
+}
+
+func TestFilterContextsByTags(t *testing.T) {
+	contexts := []string{"dev", "minikube", "prod-eu", "prod-us"}
+
+	tests := []struct {
+		name    string
+		filters []string
+		want    []string
+	}{
+		{name: "none", want: contexts},
+		{name: "key and value", filters: []string{"env=prod"}, want: []string{"prod-eu", "prod-us"}},
+		{name: "value only", filters: []string{"payments"}, want: []string{"prod-eu", "prod-us"}},
+		{name: "all filters", filters: []string{"env=prod", "region=eu"}, want: []string{"prod-eu"}},
+		{name: "no match", filters: []string{"env=staging"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filters []tagFilter
+			for _, f := range tt.filters {
+				filters = append(filters, parseTagFilter(f))
+			}
+
+			if got := filterContextsByTags(testTagConfig, contexts, filters); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterContextsByTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupContextsByTag(t *testing.T) {
+	got := groupContextsByTag(testTagConfig, []string{"prod-us", "dev", "minikube", "prod-eu"}, "env")
+
+	want := []contextGroup{
+		{Name: "env=dev", Contexts: []string{"dev"}},
+		{Name: "env=prod", Contexts: []string{"prod-us", "prod-eu"}},
+		{Name: untaggedGroup, Contexts: []string{"minikube"}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groupContextsByTag() = %v, want %v", got, want)
+	}
+}
+
+func TestContextLabel(t *testing.T) {
+	if got, want := contextLabel(testTagConfig, "prod-eu"), "prod-eu  [env=prod region=eu team=payments]"; got != want {
+		t.Errorf("contextLabel() = %q, want %q", got, want)
+	}
+
+	if got := contextLabel(testTagConfig, "minikube"); got != "minikube" {
+		t.Errorf("contextLabel() = %q, want %q", got, "minikube")
+	}
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -22,6 +25,7 @@ type ChangeAction string
 const (
 	ActionAdd    ChangeAction = "NEW"
 	ActionModify ChangeAction = "CHANGED"
+	ActionRename ChangeAction = "RENAMED"
 	ActionDelete ChangeAction = "DELETED"
 )
 
@@ -46,6 +50,10 @@ func (d KubeconfigDiff) ToChangeItems() []ChangeItem {
 		items = append(items, ChangeItem{Type: ChangeContext, Action: ActionModify, Name: x.Name, Value: x})
 	}
 
+	for _, x := range d.ContextsRenamed {
+		items = append(items, ChangeItem{Type: ChangeContext, Action: ActionRename, Name: x.From + " → " + x.To, Value: x})
+	}
+
 	for _, name := range d.ContextsDeleted {
 		items = append(items, ChangeItem{Type: ChangeContext, Action: ActionDelete, Name: name, Value: name})
 	}
@@ -59,6 +67,10 @@ func (d KubeconfigDiff) ToChangeItems() []ChangeItem {
 		items = append(items, ChangeItem{Type: ChangeCluster, Action: ActionModify, Name: x.Name, Value: x})
 	}
 
+	for _, x := range d.ClustersRenamed {
+		items = append(items, ChangeItem{Type: ChangeCluster, Action: ActionRename, Name: x.From + " → " + x.To, Value: x})
+	}
+
 	for _, name := range d.ClustersDeleted {
 		items = append(items, ChangeItem{Type: ChangeCluster, Action: ActionDelete, Name: name, Value: name})
 	}
@@ -72,6 +84,10 @@ func (d KubeconfigDiff) ToChangeItems() []ChangeItem {
 		items = append(items, ChangeItem{Type: ChangeUser, Action: ActionModify, Name: x.Name, Value: x})
 	}
 
+	for _, x := range d.UsersRenamed {
+		items = append(items, ChangeItem{Type: ChangeUser, Action: ActionRename, Name: x.From + " → " + x.To, Value: x})
+	}
+
 	for _, name := range d.UsersDeleted {
 		items = append(items, ChangeItem{Type: ChangeUser, Action: ActionDelete, Name: name, Value: name})
 	}
@@ -87,6 +103,8 @@ func applyItem(filtered *KubeconfigDiff, item ChangeItem) {
 			filtered.ContextsAdded = append(filtered.ContextsAdded, item.Value.(apiv1.NamedContext))
 		case ActionModify:
 			filtered.ContextsModified = append(filtered.ContextsModified, item.Value.(apiv1.NamedContext))
+		case ActionRename:
+			filtered.ContextsRenamed = append(filtered.ContextsRenamed, item.Value.(KubeconfigRename))
 		case ActionDelete:
 			filtered.ContextsDeleted = append(filtered.ContextsDeleted, item.Value.(string))
 		}
@@ -96,6 +114,8 @@ func applyItem(filtered *KubeconfigDiff, item ChangeItem) {
 			filtered.ClustersAdded = append(filtered.ClustersAdded, item.Value.(apiv1.NamedCluster))
 		case ActionModify:
 			filtered.ClustersModified = append(filtered.ClustersModified, item.Value.(apiv1.NamedCluster))
+		case ActionRename:
+			filtered.ClustersRenamed = append(filtered.ClustersRenamed, item.Value.(KubeconfigRename))
 		case ActionDelete:
 			filtered.ClustersDeleted = append(filtered.ClustersDeleted, item.Value.(string))
 		}
@@ -105,6 +125,8 @@ func applyItem(filtered *KubeconfigDiff, item ChangeItem) {
 			filtered.UsersAdded = append(filtered.UsersAdded, item.Value.(apiv1.NamedAuthInfo))
 		case ActionModify:
 			filtered.UsersModified = append(filtered.UsersModified, item.Value.(apiv1.NamedAuthInfo))
+		case ActionRename:
+			filtered.UsersRenamed = append(filtered.UsersRenamed, item.Value.(KubeconfigRename))
 		case ActionDelete:
 			filtered.UsersDeleted = append(filtered.UsersDeleted, item.Value.(string))
 		}
@@ -115,7 +137,7 @@ func runTUI(items []ChangeItem) (checked []bool, cancelled bool, err error) {
 	checked = make([]bool, len(items))
 
 	for i, item := range items {
-		if item.Action == ActionAdd || item.Action == ActionModify {
+		if item.Action == ActionAdd || item.Action == ActionModify || item.Action == ActionRename {
 			checked[i] = true
 		} else {
 			checked[i] = false
@@ -157,6 +179,8 @@ func runTUI(items []ChangeItem) (checked []bool, cancelled bool, err error) {
 				rawLabel = "NEW"
 			case ActionModify:
 				rawLabel = "CHANGED"
+			case ActionRename:
+				rawLabel = "RENAMED"
 			case ActionDelete:
 				rawLabel = "DELETED"
 			}
@@ -170,6 +194,8 @@ func runTUI(items []ChangeItem) (checked []bool, cancelled bool, err error) {
 				coloredLabel = "\033[32m" + paddedLabel + "\033[0m"
 			case ActionModify:
 				coloredLabel = "\033[33m" + paddedLabel + "\033[0m"
+			case ActionRename:
+				coloredLabel = "\033[36m" + paddedLabel + "\033[0m"
 			case ActionDelete:
 				coloredLabel = "\033[31m" + paddedLabel + "\033[0m"
 			}
@@ -204,6 +230,13 @@ func runTUI(items []ChangeItem) (checked []bool, cancelled bool, err error) {
 			case ' ': // Spacebar
 				checked[cursor] = !checked[cursor]
 
+				// Selecting a context also selects the new clusters and users it refers to
+				if checked[cursor] {
+					for _, dep := range referenceDependencies(items, cursor) {
+						checked[dep] = true
+					}
+				}
+
 				printMenu(false)
 			}
 		} else if n == 3 && buf[0] == 27 && buf[1] == 91 {
@@ -227,8 +260,7 @@ func runTUI(items []ChangeItem) (checked []bool, cancelled bool, err error) {
 
 // selectChanges prompts the user interactively to select which changes to apply.
 func selectChanges(diff KubeconfigDiff, originalPath, tempPath string) (KubeconfigDiff, error) {
-	items := diff.ToChangeItems()
-	if len(items) == 0 {
+	if !diff.HasChanges() {
 		return KubeconfigDiff{}, nil
 	}
 
@@ -236,8 +268,20 @@ func selectChanges(diff KubeconfigDiff, originalPath, tempPath string) (Kubeconf
 	fmt.Println("Detected modifications in your temporary session kubeconfig.")
 	fmt.Printf("  Original:  %s\n", originalPath)
 	fmt.Printf("  Temporary: %s\n\n", tempPath)
-	fmt.Println("Select which changes you want to apply back to the original kubeconfig:")
-	fmt.Println("(\033[32mNEW\033[0m and \033[33mCHANGED\033[0m items are pre-selected. Use Up/Down arrows to move, Space to toggle, Enter to confirm, Esc to cancel.)")
+
+	return pickChanges(diff, "Select which changes you want to apply back to the original kubeconfig:")
+}
+
+// pickChanges shows the interactive selector for diff below prompt and returns the selected subset.
+func pickChanges(diff KubeconfigDiff, prompt string) (KubeconfigDiff, error) {
+	items := diff.ToChangeItems()
+	if len(items) == 0 {
+		return KubeconfigDiff{}, nil
+	}
+
+	fmt.Println(prompt)
+	fmt.Println("(\033[32mNEW\033[0m, \033[33mCHANGED\033[0m and \033[36mRENAMED\033[0m items are pre-selected. Use Up/Down arrows to move, Space to toggle, Enter to confirm, Esc to cancel.)")
+	fmt.Println("(Selecting a context also selects the new clusters and users it refers to.)")
 	fmt.Println()
 
 	checked, cancelled, err := runTUI(items)
@@ -271,3 +315,26 @@ func selectChanges(diff KubeconfigDiff, originalPath, tempPath string) (Kubeconf
 
 	return filtered, nil
 }
+
+// confirm asks a yes/no question on stdin and returns true only for an explicit yes.
+// Closing stdin without answering counts as no.
+func confirm(question string) (bool, error) {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if errors.Is(err, io.EOF) && answer == "" {
+		fmt.Println()
+		logf("no answer, assuming no")
+
+		return false, nil
+	} else if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
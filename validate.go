@@ -0,0 +1,334 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/urfave/cli/v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ConfigError is a problem found in a config file.
+type ConfigError struct {
+	File string
+	Line int
+	// Key is the dotted path of the offending key, such as "check.timeout".
+	Key     string
+	Message string
+}
+
+// Error formats the problem as "file:line: key: message".
+func (e ConfigError) Error() string {
+	location := e.File
+	if e.Line > 0 {
+		location = fmt.Sprintf("%s:%d", e.File, e.Line)
+	}
+
+	if e.Key == "" {
+		return fmt.Sprintf("%s: %s", location, e.Message)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", location, e.Key, e.Message)
+}
+
+var durationType = reflect.TypeFor[Duration]()
+
+var regexpType = reflect.TypeFor[Regexp]()
+
+var envVarsType = reflect.TypeFor[EnvVars]()
+
+// jsonFieldName returns the key a struct field is read from, or "" for fields that are not decoded.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" || !field.IsExported() {
+		return ""
+	}
+
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
+
+func joinKey(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+
+	return parent + "." + key
+}
+
+// checkConfigNode checks node against the Go type it is decoded into and returns every
+// unknown key and mistyped value it finds.
+func checkConfigNode(file, key string, node *yamlv3.Node, t reflect.Type) []ConfigError {
+	if node.Kind == yamlv3.AliasNode {
+		node = node.Alias
+	}
+
+	if node.Tag == "!!null" {
+		return nil
+	}
+
+	problem := func(format string, args ...any) []ConfigError {
+		return []ConfigError{{File: file, Line: node.Line, Key: key, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	if t == durationType {
+		if node.Kind != yamlv3.ScalarNode || node.Tag != "!!str" {
+			return problem("expected a duration such as \"72h\"")
+		}
+
+		if _, err := time.ParseDuration(node.Value); err != nil {
+			return problem("invalid duration %q", node.Value)
+		}
+
+		return nil
+	}
+
+	if t == regexpType {
+		if node.Kind != yamlv3.ScalarNode || node.Tag != "!!str" {
+			return problem("expected a regular expression string")
+		}
+
+		if _, err := regexp.Compile(node.Value); err != nil {
+			return problem("invalid regular expression: %s", strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+		}
+
+		return nil
+	}
+
+	if t == envVarsType && node.Kind == yamlv3.MappingNode {
+		var problems []ConfigError
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if k := node.Content[i]; !validEnvName(k.Value) {
+				problems = append(problems, ConfigError{File: file, Line: k.Line, Key: joinKey(key, k.Value), Message: "invalid environment variable name"})
+			}
+		}
+
+		return append(problems, checkConfigNode(file, key, node, reflect.TypeFor[map[string]string]())...)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return checkConfigNode(file, key, node, t.Elem())
+	case reflect.Struct:
+		if node.Kind != yamlv3.MappingNode {
+			return problem("expected a mapping")
+		}
+
+		fields := make(map[string]reflect.StructField)
+
+		for i := range t.NumField() {
+			if name := jsonFieldName(t.Field(i)); name != "" {
+				fields[name] = t.Field(i)
+			}
+		}
+
+		var problems []ConfigError
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+
+			field, ok := fields[k.Value]
+			if !ok {
+				problems = append(problems, ConfigError{File: file, Line: k.Line, Key: joinKey(key, k.Value), Message: "unknown key"})
+
+				continue
+			}
+
+			problems = append(problems, checkConfigNode(file, joinKey(key, k.Value), v, field.Type)...)
+		}
+
+		return problems
+	case reflect.Map:
+		if node.Kind != yamlv3.MappingNode {
+			return problem("expected a mapping")
+		}
+
+		var problems []ConfigError
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			problems = append(problems, checkConfigNode(file, joinKey(key, k.Value), v, t.Elem())...)
+		}
+
+		return problems
+	case reflect.Slice:
+		if node.Kind != yamlv3.SequenceNode {
+			return problem("expected a list")
+		}
+
+		var problems []ConfigError
+
+		for i, item := range node.Content {
+			problems = append(problems, checkConfigNode(file, fmt.Sprintf("%s[%d]", key, i), item, t.Elem())...)
+		}
+
+		return problems
+	case reflect.Bool:
+		if node.Tag != "!!bool" {
+			return problem("expected true or false, got %q", node.Value)
+		}
+	case reflect.String:
+		if node.Kind != yamlv3.ScalarNode || node.Tag != "!!str" {
+			return problem("expected a string, quote the value if it is meant as one")
+		}
+	case reflect.Int, reflect.Int64:
+		if node.Tag != "!!int" {
+			return problem("expected an integer, got %q", node.Value)
+		}
+	}
+
+	return nil
+}
+
+var yamlLineRegexp = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// yamlSyntaxError converts a YAML parser error into a ConfigError carrying its line.
+func yamlSyntaxError(file string, err error) ConfigError {
+	if m := yamlLineRegexp.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+
+		return ConfigError{File: file, Line: line, Message: m[2]}
+	}
+
+	return ConfigError{File: file, Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+}
+
+// checkConfigScope reports the top-level settings of a project config other than projectConfigKeys,
+// and projectConfigKeys set in any other config file. Unknown keys are left to checkConfigNode.
+func checkConfigScope(file string, node *yamlv3.Node, project bool) []ConfigError {
+	if node.Kind == yamlv3.AliasNode {
+		node = node.Alias
+	}
+
+	if node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+
+	known := make(map[string]bool)
+
+	t := reflect.TypeFor[KswConfig]()
+	for i := range t.NumField() {
+		known[jsonFieldName(t.Field(i))] = true
+	}
+
+	var problems []ConfigError
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k := node.Content[i]
+		if !known[k.Value] || slices.Contains(projectConfigKeys, k.Value) == project {
+			continue
+		}
+
+		problem := ConfigError{File: file, Line: k.Line, Key: k.Value, Message: "only applies in a project " + projectConfigName}
+		if project {
+			problem.Message = fmt.Sprintf("not allowed in a project config, only %s and %s can be set there",
+				strings.Join(projectConfigKeys[:len(projectConfigKeys)-1], ", "), projectConfigKeys[len(projectConfigKeys)-1])
+		}
+
+		problems = append(problems, problem)
+	}
+
+	return problems
+}
+
+// parseConfig strictly decodes a config file on top of the defaults. Unknown keys and mistyped
+// values are reported as ConfigErrors with the line they appear on.
+func parseConfig(file string, b []byte) (KswConfig, error) {
+	cfg, _, err := decodeConfigLayer(defaultConfig(), file, b, isProjectConfig(file))
+
+	return cfg, err
+}
+
+// decodeConfigLayer strictly decodes a config file on top of base and returns the dotted keys
+// of the values it sets. base is left unchanged, and returned as is when the file is invalid.
+func decodeConfigLayer(base KswConfig, file string, b []byte, project bool) (KswConfig, []string, error) {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(b, &root); err != nil {
+		return base, nil, yamlSyntaxError(file, err)
+	}
+
+	// An empty file has no document node
+	if len(root.Content) == 0 {
+		return base, nil, nil
+	}
+
+	problems := checkConfigNode(file, "", root.Content[0], reflect.TypeFor[KswConfig]())
+	problems = append(problems, checkConfigScope(file, root.Content[0], project)...)
+	if len(problems) > 0 {
+		errs := make([]error, len(problems))
+		for i, p := range problems {
+			errs[i] = p
+		}
+
+		return base, nil, errors.Join(errs...)
+	}
+
+	cfg := base.clone()
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return base, nil, ConfigError{File: file, Message: err.Error()}
+	}
+
+	return cfg, configNodeKeys("", root.Content[0]), nil
+}
+
+// configNodeKeys returns the dotted keys of the scalar and list values below node.
+func configNodeKeys(key string, node *yamlv3.Node) []string {
+	if node.Kind == yamlv3.AliasNode {
+		node = node.Alias
+	}
+
+	if node.Kind != yamlv3.MappingNode {
+		return []string{key}
+	}
+
+	var keys []string
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, configNodeKeys(joinKey(key, node.Content[i].Value), node.Content[i+1])...)
+	}
+
+	return keys
+}
+
+// configValidateAction checks the given config file, or every config layer ksw would apply.
+func configValidateAction(c *cli.Context) error {
+	if path := c.Args().First(); path != "" {
+		if _, err := readConfigFile(path); err != nil {
+			fmt.Println(err)
+
+			return cli.Exit("", 1)
+		}
+
+		fmt.Printf("%s: OK\n", path)
+
+		return nil
+	}
+
+	resolved, err := loadResolvedConfig()
+	if err != nil {
+		fmt.Println(err)
+
+		return cli.Exit("", 1)
+	}
+
+	if len(resolved.Files) == 0 {
+		fmt.Println("No config file found, the default settings are used.")
+	}
+
+	for _, file := range resolved.Files {
+		fmt.Printf("%s: OK\n", file)
+	}
+
+	return nil
+}
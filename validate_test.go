@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		check   func(t *testing.T, cfg KswConfig)
+		wantErr []string
+	}{
+		{
+			name:    "empty file",
+			content: "",
+			check: func(t *testing.T, cfg KswConfig) {
+				if !reflect.DeepEqual(cfg, defaultConfig()) {
+					t.Errorf("parseConfig() = %+v, want defaults", cfg)
+				}
+			},
+		},
+		{
+			name: "valid",
+			content: `kubeconfig:
+  minify: true
+check:
+  timeout: 10s
+contexts:
+  prod-*:
+    env:
+      AWS_PROFILE: prod
+`,
+			check: func(t *testing.T, cfg KswConfig) {
+				if !cfg.Kubeconfig.Minify || time.Duration(cfg.Check.Timeout) != 10*time.Second {
+					t.Errorf("parseConfig() = %+v", cfg)
+				}
+
+				if time.Duration(cfg.Credentials.ExpiryWarning) != 7*24*time.Hour {
+					t.Errorf("parseConfig() should keep defaults for unset keys, got %+v", cfg.Credentials)
+				}
+			},
+		},
+		{
+			name:    "empty section",
+			content: "check:\n",
+		},
+		{
+			name: "unknown keys",
+			content: `kubeconfig:
+  minfy: true
+chek:
+  enabled: true
+`,
+			wantErr: []string{
+				"config.yaml:2: kubeconfig.minfy: unknown key",
+				"config.yaml:3: chek: unknown key",
+			},
+		},
+		{
+			name: "unknown key in context settings",
+			content: `contexts:
+  prod:
+    on_entr: echo hi
+`,
+			wantErr: []string{"config.yaml:3: contexts.prod.on_entr: unknown key"},
+		},
+		{
+			name:    "mistyped bool",
+			content: "kubeconfig:\n  minify: yes\n",
+			wantErr: []string{`config.yaml:2: kubeconfig.minify: expected true or false, got "yes"`},
+		},
+		{
+			name:    "invalid duration",
+			content: "check:\n  timeout: 5 seconds\n",
+			wantErr: []string{`config.yaml:2: check.timeout: invalid duration "5 seconds"`},
+		},
+		{
+			name:    "unquoted number",
+			content: "contexts:\n  dev:\n    env:\n      PORT: 8080\n",
+			wantErr: []string{"config.yaml:4: contexts.dev.env.PORT: expected a string"},
+		},
+		{
+			name:    "invalid env name",
+			content: "contexts:\n  dev:\n    env:\n      X;rm -rf ~: y\n",
+			wantErr: []string{"config.yaml:4: contexts.dev.env.X;rm -rf ~: invalid environment variable name"},
+		},
+		{
+			name:    "mapping expected",
+			content: "session: /tmp\n",
+			wantErr: []string{"config.yaml:1: session: expected a mapping"},
+		},
+		{
+			name:    "project settings in the user config",
+			content: "context: staging\nnamespace: payments\n",
+			wantErr: []string{
+				"config.yaml:1: context: only applies in a project .ksw.yaml",
+				"config.yaml:2: namespace: only applies in a project .ksw.yaml",
+			},
+		},
+		{
+			name:    "invalid yaml",
+			content: "kubeconfig:\n  minify: true\n bad",
+			wantErr: []string{"config.yaml:2: did not find expected key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig("config.yaml", []byte(tt.content))
+
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatalf("parseConfig() expected error")
+				}
+
+				lines := strings.Split(err.Error(), "\n")
+				if len(lines) != len(tt.wantErr) {
+					t.Fatalf("parseConfig() error =\n%v\nwant %d problem(s)", err, len(tt.wantErr))
+				}
+
+				for i, want := range tt.wantErr {
+					if !strings.Contains(lines[i], want) {
+						t.Errorf("problem %d = %q, want containing %q", i, lines[i], want)
+					}
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseConfig() error = %v", err)
+			}
+
+			if tt.check != nil {
+				tt.check(t, cfg)
+			}
+		})
+	}
+}
+
+// schemaNode is the subset of JSON Schema used by docs/config.schema.json.
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+	Defs                 map[string]*schemaNode `json:"$defs"`
+}
+
+// checkSchema verifies that node describes the same keys as the Go type typ.
+func checkSchema(t *testing.T, key string, node *schemaNode, typ reflect.Type, defs map[string]*schemaNode) {
+	t.Helper()
+
+	if node.Ref != "" {
+		if typ == durationType {
+			if node.Ref != "#/$defs/duration" {
+				t.Errorf("%s: duration should refer to #/$defs/duration, got %s", key, node.Ref)
+			}
+
+			return
+		}
+
+		node = defs[strings.TrimPrefix(node.Ref, "#/$defs/")]
+		if node == nil {
+			t.Fatalf("%s: unresolved $ref", key)
+		}
+	}
+
+	switch typ.Kind() {
+	case reflect.Pointer:
+		checkSchema(t, key, node, typ.Elem(), defs)
+	case reflect.Struct:
+		fields := make(map[string]reflect.Type)
+
+		for i := range typ.NumField() {
+			if name := jsonFieldName(typ.Field(i)); name != "" {
+				fields[name] = typ.Field(i).Type
+			}
+		}
+
+		if got, want := slices.Sorted(maps.Keys(node.Properties)), slices.Sorted(maps.Keys(fields)); !slices.Equal(got, want) {
+			t.Errorf("%s: schema properties = %v, config keys = %v", key, got, want)
+		}
+
+		if string(node.AdditionalProperties) != "false" {
+			t.Errorf("%s: schema should not allow additional properties", key)
+		}
+
+		for name, fieldType := range fields {
+			if prop := node.Properties[name]; prop != nil {
+				checkSchema(t, joinKey(key, name), prop, fieldType, defs)
+			}
+		}
+	case reflect.Map:
+		var elem schemaNode
+		if err := json.Unmarshal(node.AdditionalProperties, &elem); err != nil {
+			t.Fatalf("%s: additionalProperties should be a schema: %v", key, err)
+		}
+
+		checkSchema(t, key+".*", &elem, typ.Elem(), defs)
+	case reflect.Bool:
+		if node.Type != "boolean" {
+			t.Errorf("%s: schema type = %q, want boolean", key, node.Type)
+		}
+	case reflect.String:
+		if node.Type != "string" {
+			t.Errorf("%s: schema type = %q, want string", key, node.Type)
+		}
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	b, err := os.ReadFile("docs/config.schema.json")
+	if err != nil {
+		t.Fatalf("Failed to read schema: %v", err)
+	}
+
+	var schema schemaNode
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	checkSchema(t, "", &schema, reflect.TypeFor[KswConfig](), schema.Defs)
+}